@@ -0,0 +1,42 @@
+package sentinel
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// Addr is a network address of a redis instance as announced by sentinel.
+type Addr struct {
+	Host string
+	Port int
+}
+
+// String returns address in a host:port form suitable for dialing. IPv6 hosts
+// are enclosed in square brackets.
+func (a Addr) String() string {
+	return net.JoinHostPort(a.Host, strconv.Itoa(a.Port))
+}
+
+// AddrError is returned when sentinel replies with an address that can not be
+// parsed into Addr.
+type AddrError struct {
+	Reply  []string
+	Reason string
+}
+
+func (e *AddrError) Error() string {
+	return fmt.Sprintf("sentinel: invalid address reply %q: %s", e.Reply, e.Reason)
+}
+
+// parseAddr parses two element host, port reply returned by sentinel.
+func parseAddr(res []string) (Addr, error) {
+	if len(res) != 2 {
+		return Addr{}, &AddrError{Reply: res, Reason: "expected exactly two elements"}
+	}
+	port, err := strconv.Atoi(res[1])
+	if err != nil {
+		return Addr{}, &AddrError{Reply: res, Reason: "port is not numeric"}
+	}
+	return Addr{Host: res[0], Port: port}, nil
+}
@@ -0,0 +1,271 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+var (
+	// ErrNoGoodSlave is returned when sentinel refuses to start a failover
+	// because there is no replica suitable for promotion.
+	ErrNoGoodSlave = errors.New("sentinel: no suitable replica to promote")
+	// ErrFailoverInProgress is returned when sentinel refuses to start a
+	// failover because another one is already in progress.
+	ErrFailoverInProgress = errors.New("sentinel: failover already in progress")
+	// ErrDuplicateMaster is returned by MonitorMaster when sentinel already
+	// monitors a master with the same name.
+	ErrDuplicateMaster = errors.New("sentinel: duplicated master name")
+	// ErrUnknownParameter is returned by ConfigGet and ConfigSet when
+	// sentinel does not recognize the global configuration parameter.
+	ErrUnknownParameter = errors.New("sentinel: unknown configuration parameter")
+	// ErrDangerousCommand is returned when a testing-only command is called
+	// on Client created without WithDangerousCommands option.
+	ErrDangerousCommand = errors.New("sentinel: dangerous commands are not allowed")
+)
+
+// Failover forces a failover of the named master as if it was not reachable,
+// without asking other sentinels for agreement.
+//
+// The command is issued to the active sentinel only. Other sentinels are tried
+// only if the active one can not be dialed, failures after the command was
+// sent are returned to the caller without retrying, so a failover is never
+// requested twice.
+func (sc *Client) Failover(name string) error {
+	_, err := sc.doActive("SENTINEL", "FAILOVER", name)
+	return adminError(err)
+}
+
+// Reset resets all the masters with names matching the glob-style pattern,
+// clearing their state including known replicas and sentinels. It returns the
+// number of masters that matched the pattern.
+//
+// Reset is issued to the active sentinel only and is not propagated to the
+// rest of the sentinels, in the same way as Failover.
+func (sc *Client) Reset(pattern string) (int, error) {
+	return redis.Int(sc.doActive("SENTINEL", "RESET", pattern))
+}
+
+// FlushConfig forces the active sentinel to rewrite its configuration on
+// disk, including the current sentinel state. An error is returned if sentinel
+// fails to persist the configuration.
+func (sc *Client) FlushConfig() error {
+	_, err := sc.doActive("SENTINEL", "FLUSHCONFIG")
+	return err
+}
+
+// MonitorMaster tells the active sentinel to start monitoring a new master
+// with the specified name, address and quorum.
+//
+// Like all the commands changing sentinel configuration it is issued to a
+// single sentinel only, other sentinels need to be configured separately.
+func (sc *Client) MonitorMaster(name, ip string, port, quorum int) error {
+	if name == "" {
+		return errors.New("sentinel: master name is not set")
+	}
+	if ip == "" {
+		return errors.New("sentinel: master ip is not set")
+	}
+	if port <= 0 || port > 65535 {
+		return fmt.Errorf("sentinel: invalid master port %d", port)
+	}
+	if quorum < 1 {
+		return fmt.Errorf("sentinel: invalid quorum %d", quorum)
+	}
+
+	_, err := sc.doActive("SENTINEL", "MONITOR", name, ip, port, quorum)
+	return adminError(err)
+}
+
+// RemoveMaster tells the active sentinel to stop monitoring the named master
+// and remove it from its state. It is issued to a single sentinel only.
+func (sc *Client) RemoveMaster(name string) error {
+	if name == "" {
+		return errors.New("sentinel: master name is not set")
+	}
+
+	_, err := sc.doActive("SENTINEL", "REMOVE", name)
+	return adminError(err)
+}
+
+// SetOption changes configuration option of the named master on the active
+// sentinel, e.g. down-after-milliseconds or quorum. It is issued to a single
+// sentinel only.
+func (sc *Client) SetOption(name, option, value string) error {
+	if name == "" {
+		return errors.New("sentinel: master name is not set")
+	}
+	if option == "" {
+		return errors.New("sentinel: option is not set")
+	}
+
+	_, err := sc.doActive("SENTINEL", "SET", name, option, value)
+	return adminError(err)
+}
+
+// ConfigGet returns global sentinel configuration parameters matching the
+// glob-style pattern, e.g. "resolve-hostnames" or "announce-*". Requires
+// sentinel 6.2 or newer.
+func (sc *Client) ConfigGet(param string) (map[string]string, error) {
+	res, err := redis.StringMap(sc.do("SENTINEL", "CONFIG", "GET", param))
+	if isUnknownParameter(err) {
+		return nil, ErrUnknownParameter
+	}
+	return res, err
+}
+
+// ConfigSet changes global sentinel configuration parameter on the active
+// sentinel. Requires sentinel 6.2 or newer. It is issued to a single sentinel
+// only.
+func (sc *Client) ConfigSet(param, value string) error {
+	_, err := sc.doActive("SENTINEL", "CONFIG", "SET", param, value)
+	if isUnknownParameter(err) {
+		return ErrUnknownParameter
+	}
+	return err
+}
+
+// isUnknownParameter checks if err is an error reply of SENTINEL CONFIG for
+// a parameter sentinel does not know about.
+func isUnknownParameter(err error) bool {
+	var rerr redis.Error
+	return errors.As(err, &rerr) && strings.Contains(strings.ToLower(string(rerr)), "unknown parameter")
+}
+
+// SimulateFailure configures the active sentinel to crash after being elected
+// as a failover leader, after promoting a replica, or both. Passing false for
+// both flags clears the simulation. It is meant for chaos testing only and
+// fails with ErrDangerousCommand unless the client was created with the
+// WithDangerousCommands option.
+func (sc *Client) SimulateFailure(crashAfterElection, crashAfterPromotion bool) error {
+	if !crashAfterElection && !crashAfterPromotion {
+		return sc.ClearSimulatedFailure()
+	}
+	if !sc.allowDangerous {
+		return ErrDangerousCommand
+	}
+
+	args := []interface{}{"SIMULATE-FAILURE"}
+	if crashAfterElection {
+		args = append(args, "crash-after-election")
+	}
+	if crashAfterPromotion {
+		args = append(args, "crash-after-promotion")
+	}
+
+	_, err := sc.doActive("SENTINEL", args...)
+	return err
+}
+
+// ClearSimulatedFailure clears failure simulation flags set by
+// SimulateFailure on the active sentinel.
+func (sc *Client) ClearSimulatedFailure() error {
+	if !sc.allowDangerous {
+		return ErrDangerousCommand
+	}
+
+	// Sentinel resets simulation flags before parsing the arguments, so
+	// requesting the help text is the way to clear them.
+	_, err := sc.doActive("SENTINEL", "SIMULATE-FAILURE", "help")
+	return err
+}
+
+// QuorumStatus is a result of the SENTINEL CKQUORUM check.
+type QuorumStatus struct {
+	// Usable is the number of reachable sentinels monitoring the master,
+	// including the sentinel that performed the check.
+	Usable int
+	// Quorum is the number of sentinels that need to agree that the master is
+	// not reachable.
+	Quorum int
+}
+
+// QuorumError is returned by CkQuorum when the sentinels monitoring the master
+// can not reach the quorum or the majority needed to authorize a failover.
+type QuorumError struct {
+	QuorumStatus
+	Reason string
+}
+
+func (e *QuorumError) Error() string {
+	return fmt.Sprintf("sentinel: no quorum: %d usable sentinels, quorum %d: %s", e.Usable, e.Quorum, e.Reason)
+}
+
+// CkQuorum checks if the current sentinel configuration is able to reach the
+// quorum needed to failover the named master, and the majority needed to
+// authorize the failover. Both the master configuration and the check come
+// from the same sentinel. *QuorumError is returned along with the status if
+// the check fails, the other sentinels are not asked in that case.
+func (sc *Client) CkQuorum(name string) (QuorumStatus, error) {
+	ctx := context.Background()
+	reply, addr, err := sc.doAddr(ctx, "SENTINEL", "master", name)
+	m, err := redis.StringMap(reply, err)
+	if isNoSuchMaster(err) {
+		return QuorumStatus{}, ErrMasterUnknown
+	}
+	if err != nil {
+		return QuorumStatus{}, err
+	}
+	master, err := parseMasterInfo(m)
+	if err != nil {
+		return QuorumStatus{}, err
+	}
+
+	reply, _, err = sc.doOnce(ctx, addr, false, "SENTINEL", "CKQUORUM", name)
+	res, err := redis.String(reply, err)
+	if rerr, ok := err.(redis.Error); ok && strings.HasPrefix(string(rerr), "NOQUORUM") {
+		status, reason, perr := parseQuorumReply(strings.TrimPrefix(string(rerr), "NOQUORUM "))
+		if perr != nil {
+			return QuorumStatus{}, perr
+		}
+		status.Quorum = master.Quorum
+		return status, &QuorumError{QuorumStatus: status, Reason: reason}
+	}
+	if err != nil {
+		return QuorumStatus{}, adminError(err)
+	}
+
+	status, _, err := parseQuorumReply(strings.TrimPrefix(res, "OK "))
+	if err != nil {
+		return QuorumStatus{}, err
+	}
+	status.Quorum = master.Quorum
+	return status, nil
+}
+
+// parseQuorumReply parses CKQUORUM status message, e.g. "3 usable Sentinels.
+// Quorum and failover authorization can be reached" and returns the usable
+// sentinel count and the rest of the message.
+func parseQuorumReply(msg string) (QuorumStatus, string, error) {
+	var status QuorumStatus
+	if _, err := fmt.Sscanf(msg, "%d usable", &status.Usable); err != nil {
+		return status, "", fmt.Errorf("sentinel: unexpected ckquorum reply: %q", msg)
+	}
+	reason := msg
+	if i := strings.Index(msg, ". "); i >= 0 {
+		reason = msg[i+2:]
+	}
+	return status, reason, nil
+}
+
+// adminError maps well known sentinel error replies to the package errors.
+func adminError(err error) error {
+	var rerr redis.Error
+	if !errors.As(err, &rerr) {
+		return err
+	}
+	switch {
+	case strings.HasPrefix(string(rerr), "NOGOODSLAVE"):
+		return ErrNoGoodSlave
+	case strings.HasPrefix(string(rerr), "INPROG"):
+		return ErrFailoverInProgress
+	case isNoSuchMaster(rerr):
+		return ErrMasterUnknown
+	case strings.Contains(string(rerr), "Duplicated master name"):
+		return ErrDuplicateMaster
+	}
+	return err
+}
@@ -0,0 +1,436 @@
+package sentinel
+
+import (
+	"errors"
+	"net"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// quorumHandler answers SENTINEL CKQUORUM with reply.
+func quorumHandler(quorum, reply string) func(args []string) string {
+	return func(args []string) string {
+		if strings.EqualFold(args[0], "SENTINEL") && len(args) > 1 {
+			switch strings.ToLower(args[1]) {
+			case "master":
+				return bulkArr("name", "mymaster", "ip", "10.0.0.1", "port", "6379", "flags", "master", "quorum", quorum)
+			case "ckquorum":
+				return reply
+			}
+		}
+		return basicHandler(args)
+	}
+}
+
+func TestCkQuorum(t *testing.T) {
+	s := newFake(t, quorumHandler("2", "+OK 3 usable Sentinels. Quorum and failover authorization can be reached\r\n"))
+	c := NewClient([]string{s.addr()})
+	defer c.Close()
+
+	status, err := c.CkQuorum("mymaster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (QuorumStatus{Usable: 3, Quorum: 2}); status != want {
+		t.Fatalf("CkQuorum() = %+v, want %+v", status, want)
+	}
+}
+
+func TestCkQuorumNoQuorum(t *testing.T) {
+	first := newFake(t, quorumHandler("2", "-NOQUORUM 1 usable Sentinels. Not enough available Sentinels to reach the specified quorum for this master\r\n"))
+	second := newFake(t, quorumHandler("2", "+OK 3 usable Sentinels. Quorum and failover authorization can be reached\r\n"))
+	c := NewClient([]string{first.addr(), second.addr()})
+	defer c.Close()
+
+	status, err := c.CkQuorum("mymaster")
+	var qerr *QuorumError
+	if !errors.As(err, &qerr) {
+		t.Fatalf("CkQuorum() error = %v, want *QuorumError", err)
+	}
+	if want := (QuorumStatus{Usable: 1, Quorum: 2}); status != want || qerr.QuorumStatus != want {
+		t.Fatalf("CkQuorum() = %+v, %+v, want %+v", status, qerr.QuorumStatus, want)
+	}
+	if !strings.HasPrefix(qerr.Reason, "Not enough available Sentinels") {
+		t.Errorf("Reason = %q", qerr.Reason)
+	}
+	if n := second.calls("SENTINEL CKQUORUM mymaster") + second.calls("SENTINEL master mymaster"); n != 0 {
+		t.Errorf("second sentinel queried %d times", n)
+	}
+}
+
+func TestCkQuorumSameSentinel(t *testing.T) {
+	// First sentinel does not know the master, so both commands go to the
+	// second one.
+	first := newFake(t, basicHandler)
+	second := newFake(t, quorumHandler("2", "+OK 2 usable Sentinels. Quorum and failover authorization can be reached\r\n"))
+	c := NewClient([]string{first.addr(), second.addr()})
+	defer c.Close()
+
+	if _, err := c.CkQuorum("mymaster"); err != nil {
+		t.Fatal(err)
+	}
+	if n := first.calls("SENTINEL CKQUORUM mymaster"); n != 0 {
+		t.Errorf("CKQUORUM sent to the first sentinel %d times", n)
+	}
+	if n := second.calls("SENTINEL CKQUORUM mymaster"); n != 1 {
+		t.Errorf("CKQUORUM sent to the second sentinel %d times, want 1", n)
+	}
+}
+
+// replyFailover answers SENTINEL FAILOVER with reply.
+func replyFailover(reply string) func(args []string) string {
+	return func(args []string) string {
+		if strings.EqualFold(args[0], "SENTINEL") && len(args) > 1 && strings.EqualFold(args[1], "failover") {
+			return reply
+		}
+		return basicHandler(args)
+	}
+}
+
+// closedAddr returns address nothing is listening on.
+func closedAddr(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+func TestFailoverErrors(t *testing.T) {
+	tests := []struct {
+		reply string
+		want  error
+	}{
+		{"+OK\r\n", nil},
+		{"-NOGOODSLAVE No suitable replica to promote\r\n", ErrNoGoodSlave},
+		{"-INPROG Failover already in progress\r\n", ErrFailoverInProgress},
+		{"-ERR No such master with that name\r\n", ErrMasterUnknown},
+	}
+	for _, tt := range tests {
+		s := newFake(t, replyFailover(tt.reply))
+		c := NewClient([]string{s.addr()})
+		err := c.Failover("mymaster")
+		c.Close()
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%q: Failover() error = %v, want %v", tt.reply, err, tt.want)
+		}
+		if n := s.calls("SENTINEL FAILOVER mymaster"); n != 1 {
+			t.Errorf("%q: FAILOVER sent %d times, want 1", tt.reply, n)
+		}
+	}
+}
+
+func TestFailoverNotRetried(t *testing.T) {
+	first := newFake(t, replyFailover("-INPROG Failover already in progress\r\n"))
+	second := newFake(t, replyFailover("+OK\r\n"))
+	c := NewClient([]string{first.addr(), second.addr()})
+	defer c.Close()
+
+	if err := c.Failover("mymaster"); err != ErrFailoverInProgress {
+		t.Fatalf("Failover() error = %v, want ErrFailoverInProgress", err)
+	}
+	if n := second.calls("SENTINEL FAILOVER mymaster"); n != 0 {
+		t.Errorf("FAILOVER sent to the second sentinel %d times", n)
+	}
+}
+
+func TestFailoverSkipsUndialable(t *testing.T) {
+	s := newFake(t, replyFailover("+OK\r\n"))
+	c := NewClient([]string{closedAddr(t), s.addr()})
+	defer c.Close()
+
+	if err := c.Failover("mymaster"); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.calls("SENTINEL FAILOVER mymaster"); n != 1 {
+		t.Errorf("FAILOVER sent %d times, want 1", n)
+	}
+}
+
+func TestReset(t *testing.T) {
+	handler := func(args []string) string {
+		if strings.EqualFold(args[0], "SENTINEL") && len(args) == 3 && strings.EqualFold(args[1], "reset") {
+			return ":2\r\n"
+		}
+		return basicHandler(args)
+	}
+	a, b := newFake(t, handler), newFake(t, handler)
+	c := NewClient([]string{a.addr(), b.addr()})
+	defer c.Close()
+
+	n, err := c.Reset("my*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("Reset() = %d, want 2", n)
+	}
+	if got := a.calls("SENTINEL RESET my*") + b.calls("SENTINEL RESET my*"); got != 1 {
+		t.Errorf("RESET sent %d times, want 1", got)
+	}
+}
+
+// configHandler answers SENTINEL CONFIG with the parameters in conf, in the
+// reply shape of sentinel 6.2 if single is set and of 7.x otherwise.
+func configHandler(conf map[string]string, single bool) func(args []string) string {
+	var mu sync.Mutex
+	return func(args []string) string {
+		if !strings.EqualFold(args[0], "SENTINEL") || len(args) < 4 || !strings.EqualFold(args[1], "config") {
+			return basicHandler(args)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		param := strings.ToLower(args[3])
+		switch strings.ToLower(args[2]) {
+		case "set":
+			if _, ok := conf[param]; !ok || len(args) != 5 {
+				return "-ERR Invalid argument '" + args[3] + "' to SENTINEL CONFIG SET\r\n"
+			}
+			conf[param] = args[4]
+			return "+OK\r\n"
+		case "get":
+			var items []string
+			for _, k := range []string{"announce-hostnames", "announce-ip", "resolve-hostnames"} {
+				if ok, _ := path.Match(param, k); ok {
+					items = append(items, k, conf[k])
+				}
+			}
+			if single && len(items) > 2 {
+				items = items[:2]
+			}
+			if single && len(items) == 0 {
+				return "-ERR Unknown parameter '" + args[3] + "' to SENTINEL CONFIG GET\r\n"
+			}
+			return bulkArr(items...)
+		}
+		return basicHandler(args)
+	}
+}
+
+func TestConfigGet(t *testing.T) {
+	tests := []struct {
+		single bool
+		param  string
+		want   map[string]string
+	}{
+		{true, "resolve-hostnames", map[string]string{"resolve-hostnames": "no"}},
+		{false, "resolve-hostnames", map[string]string{"resolve-hostnames": "no"}},
+		{false, "*-hostnames", map[string]string{"announce-hostnames": "yes", "resolve-hostnames": "no"}},
+		{false, "no-such-param", map[string]string{}},
+	}
+	for _, tt := range tests {
+		conf := map[string]string{"announce-hostnames": "yes", "announce-ip": "", "resolve-hostnames": "no"}
+		s := newFake(t, configHandler(conf, tt.single))
+		c := NewClient([]string{s.addr()})
+		got, err := c.ConfigGet(tt.param)
+		c.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.param, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ConfigGet(%q) = %v, want %v", tt.param, got, tt.want)
+		}
+	}
+}
+
+func TestConfigUnknownParameter(t *testing.T) {
+	s := newFake(t, func(args []string) string {
+		if strings.EqualFold(args[0], "SENTINEL") && len(args) > 2 && strings.EqualFold(args[1], "config") {
+			return "-ERR Unknown parameter 'bogus'\r\n"
+		}
+		return basicHandler(args)
+	})
+	c := NewClient([]string{s.addr()})
+	defer c.Close()
+
+	if _, err := c.ConfigGet("bogus"); err != ErrUnknownParameter {
+		t.Errorf("ConfigGet() error = %v, want ErrUnknownParameter", err)
+	}
+	if err := c.ConfigSet("bogus", "yes"); err != ErrUnknownParameter {
+		t.Errorf("ConfigSet() error = %v, want ErrUnknownParameter", err)
+	}
+}
+
+func TestConfigSet(t *testing.T) {
+	handler := configHandler(map[string]string{"announce-hostnames": "no", "announce-ip": "", "resolve-hostnames": "no"}, false)
+	a, b := newFake(t, handler), newFake(t, handler)
+	c := NewClient([]string{a.addr(), b.addr()})
+	defer c.Close()
+
+	if err := c.ConfigSet("resolve-hostnames", "yes"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.ConfigGet("resolve-hostnames")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["resolve-hostnames"] != "yes" {
+		t.Errorf("resolve-hostnames = %q, want yes", got["resolve-hostnames"])
+	}
+	if n := a.calls("SENTINEL CONFIG SET resolve-hostnames yes") + b.calls("SENTINEL CONFIG SET resolve-hostnames yes"); n != 1 {
+		t.Errorf("CONFIG SET sent %d times, want 1", n)
+	}
+}
+
+func TestFlushConfig(t *testing.T) {
+	failing := int32(0)
+	handler := func(args []string) string {
+		if strings.EqualFold(args[0], "SENTINEL") && len(args) == 2 && strings.EqualFold(args[1], "flushconfig") {
+			if atomic.LoadInt32(&failing) == 1 {
+				return "-ERR Failed to save config\r\n"
+			}
+			return "+OK\r\n"
+		}
+		return basicHandler(args)
+	}
+	a, b := newFake(t, handler), newFake(t, handler)
+	c := NewClient([]string{a.addr(), b.addr()})
+	defer c.Close()
+
+	if err := c.FlushConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.calls("SENTINEL FLUSHCONFIG") + b.calls("SENTINEL FLUSHCONFIG"); got != 1 {
+		t.Errorf("FLUSHCONFIG sent %d times, want 1", got)
+	}
+
+	atomic.StoreInt32(&failing, 1)
+	var rerr redis.Error
+	if err := c.FlushConfig(); !errors.As(err, &rerr) || !strings.Contains(string(rerr), "Failed to save config") {
+		t.Errorf("FlushConfig() error = %v, want sentinel error reply", err)
+	}
+	if got := a.calls("SENTINEL FLUSHCONFIG") + b.calls("SENTINEL FLUSHCONFIG"); got != 2 {
+		t.Errorf("FLUSHCONFIG sent %d times, want failure not retried", got)
+	}
+}
+
+func TestMonitorMaster(t *testing.T) {
+	var mu sync.Mutex
+	monitored := map[string]bool{"mymaster": true}
+	s := newFake(t, func(args []string) string {
+		if !strings.EqualFold(args[0], "SENTINEL") || len(args) < 3 {
+			return basicHandler(args)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch strings.ToUpper(args[1]) {
+		case "MONITOR":
+			if monitored[args[2]] {
+				return "-ERR Duplicated master name\r\n"
+			}
+			monitored[args[2]] = true
+			return "+OK\r\n"
+		case "REMOVE", "SET":
+			if !monitored[args[2]] {
+				return "-ERR No such master with that name\r\n"
+			}
+			if strings.EqualFold(args[1], "REMOVE") {
+				delete(monitored, args[2])
+			}
+			return "+OK\r\n"
+		}
+		return basicHandler(args)
+	})
+	c := NewClient([]string{s.addr()})
+	defer c.Close()
+
+	if err := c.MonitorMaster("other", "10.0.0.5", 6379, 2); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.calls("SENTINEL MONITOR other 10.0.0.5 6379 2"); n != 1 {
+		t.Errorf("MONITOR sent %d times, want 1", n)
+	}
+	if err := c.MonitorMaster("other", "10.0.0.5", 6379, 2); !errors.Is(err, ErrDuplicateMaster) {
+		t.Errorf("MonitorMaster() error = %v, want ErrDuplicateMaster", err)
+	}
+	if err := c.SetOption("other", "down-after-milliseconds", "5000"); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.calls("SENTINEL SET other down-after-milliseconds 5000"); n != 1 {
+		t.Errorf("SET sent %d times, want 1", n)
+	}
+	if err := c.RemoveMaster("other"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.RemoveMaster("other"); !errors.Is(err, ErrMasterUnknown) {
+		t.Errorf("RemoveMaster() error = %v, want ErrMasterUnknown", err)
+	}
+	if err := c.SetOption("other", "quorum", "2"); !errors.Is(err, ErrMasterUnknown) {
+		t.Errorf("SetOption() error = %v, want ErrMasterUnknown", err)
+	}
+}
+
+func TestMonitorMasterInvalid(t *testing.T) {
+	s := newFake(t, basicHandler)
+	c := NewClient([]string{s.addr()})
+	defer c.Close()
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"no name", c.MonitorMaster("", "10.0.0.5", 6379, 2)},
+		{"no ip", c.MonitorMaster("other", "", 6379, 2)},
+		{"bad port", c.MonitorMaster("other", "10.0.0.5", 0, 2)},
+		{"bad quorum", c.MonitorMaster("other", "10.0.0.5", 6379, 0)},
+		{"remove no name", c.RemoveMaster("")},
+		{"set no name", c.SetOption("", "quorum", "2")},
+		{"set no option", c.SetOption("other", "", "2")},
+	}
+	for _, tt := range tests {
+		if tt.err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+	if n := s.dialed(); n != 0 {
+		t.Errorf("sentinel dialed %d times for invalid arguments", n)
+	}
+}
+
+func TestSimulateFailure(t *testing.T) {
+	s := newFake(t, func(args []string) string {
+		if len(args) > 1 && strings.EqualFold(args[1], "simulate-failure") {
+			return "+OK\r\n"
+		}
+		return basicHandler(args)
+	})
+
+	guarded := NewClient([]string{s.addr()})
+	defer guarded.Close()
+	if err := guarded.SimulateFailure(true, false); !errors.Is(err, ErrDangerousCommand) {
+		t.Errorf("SimulateFailure() error = %v, want ErrDangerousCommand", err)
+	}
+	if err := guarded.ClearSimulatedFailure(); !errors.Is(err, ErrDangerousCommand) {
+		t.Errorf("ClearSimulatedFailure() error = %v, want ErrDangerousCommand", err)
+	}
+	if n := s.dialed(); n != 0 {
+		t.Fatalf("sentinel dialed %d times without WithDangerousCommands", n)
+	}
+
+	c := NewClientWithOptions([]string{s.addr()}, WithDangerousCommands())
+	defer c.Close()
+	for _, cmd := range []struct {
+		election, promotion bool
+		want                string
+	}{
+		{true, false, "SENTINEL SIMULATE-FAILURE crash-after-election"},
+		{false, true, "SENTINEL SIMULATE-FAILURE crash-after-promotion"},
+		{true, true, "SENTINEL SIMULATE-FAILURE crash-after-election crash-after-promotion"},
+		{false, false, "SENTINEL SIMULATE-FAILURE help"},
+	} {
+		if err := c.SimulateFailure(cmd.election, cmd.promotion); err != nil {
+			t.Fatal(err)
+		}
+		if n := s.calls(cmd.want); n != 1 {
+			t.Errorf("%q sent %d times, want 1", cmd.want, n)
+		}
+	}
+}
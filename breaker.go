@@ -0,0 +1,56 @@
+package sentinel
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// breakerAllows reports if queries can be sent to the sentinel at addr. The
+// sentinel is skipped for the cooldown after failing breakerThreshold times
+// in a row, see WithCircuitBreaker. Once the cooldown elapses a single query
+// is let through to probe the sentinel.
+func (sc *Client) breakerAllows(addr string) bool {
+	sc.Lock()
+	defer sc.Unlock()
+
+	if sc.breakerThreshold <= 0 {
+		return true
+	}
+	st := sc.sentinelState(addr)
+	if st.failures < sc.breakerThreshold {
+		return true
+	}
+	if time.Now().Before(st.cooldownUntil) || st.probing {
+		return false
+	}
+	st.probing = true
+	return true
+}
+
+// recordAttempt updates consecutive failure count of the sentinel at addr
+// after a query ended with err. Error replies are not failures, as the
+// sentinel is reachable. Queries stopped because ctx is done are not counted.
+func (sc *Client) recordAttempt(ctx context.Context, addr string, err error) {
+	sc.Lock()
+	defer sc.Unlock()
+
+	if sc.breakerThreshold <= 0 {
+		return
+	}
+	st := sc.sentinelState(addr)
+	st.probing = false
+	if _, ok := err.(redis.Error); err == nil || ok {
+		st.failures = 0
+		st.cooldownUntil = time.Time{}
+		return
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	st.failures++
+	if st.failures >= sc.breakerThreshold {
+		st.cooldownUntil = time.Now().Add(sc.breakerCooldown)
+	}
+}
@@ -0,0 +1,51 @@
+package sentinel
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	const cooldown = 50 * time.Millisecond
+	down, s := closedAddr(t), newFake(t, basicHandler)
+	var d recordingDialer
+	c := NewClientWithOptions([]string{down, s.addr()}, WithDialer(d.dial),
+		WithCircuitBreaker(2, cooldown), WithStickiness(StickinessPreferFirst))
+	defer c.Close()
+
+	dialedDown := func() int {
+		n := 0
+		for _, addr := range d.dialed() {
+			if addr == down {
+				n++
+			}
+		}
+		return n
+	}
+	lookup := func() {
+		t.Helper()
+		if _, err := c.MasterAddress("mymaster"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lookup()
+	lookup()
+	if st := c.Stats()[0]; st.Failures != 2 || st.CooldownUntil.IsZero() {
+		t.Fatalf("failing sentinel stats %+v, want in cooldown after 2 failures", st)
+	}
+	lookup()
+	if n := dialedDown(); n != 2 {
+		t.Errorf("failing sentinel dialed %d times, want skipped in cooldown", n)
+	}
+
+	time.Sleep(cooldown)
+	lookup()
+	if n := dialedDown(); n != 3 {
+		t.Errorf("failing sentinel dialed %d times, want probed after cooldown", n)
+	}
+	lookup()
+	if n := dialedDown(); n != 3 {
+		t.Errorf("failing sentinel dialed %d times, want skipped after failed probe", n)
+	}
+}
@@ -0,0 +1,70 @@
+package sentinel
+
+import (
+	"time"
+)
+
+// resolvedAddr is a master address resolved by sentinel.
+type resolvedAddr struct {
+	addr     Addr
+	resolved time.Time
+	// invalid is set for addresses that must not be served from the cache,
+	// but are still reported as last known.
+	invalid bool
+}
+
+// Invalidate removes the cached address of the named master, so the next
+// lookup queries sentinel. It should be called when the connection to the
+// cached address turns out not to be a master.
+func (sc *Client) Invalidate(name string) {
+	sc.Lock()
+	defer sc.Unlock()
+
+	if r, ok := sc.masters[name]; ok {
+		r.invalid = true
+		sc.masters[name] = r
+	}
+}
+
+// LastKnownMasterAddress returns the named master address resolved by the
+// last successful lookup and the time it was resolved at, without querying
+// sentinel. False is returned if the master was never resolved. Address is
+// reported even if it was invalidated or is older than the cache ttl.
+func (sc *Client) LastKnownMasterAddress(name string) (string, time.Time, bool) {
+	sc.Lock()
+	defer sc.Unlock()
+
+	r, ok := sc.masters[name]
+	if !ok {
+		return "", time.Time{}, false
+	}
+	return r.addr.String(), r.resolved, true
+}
+
+// cachedMasterAddr returns cached address of the named master if caching is
+// enabled and the address was resolved within the cache ttl.
+func (sc *Client) cachedMasterAddr(name string) (Addr, bool) {
+	sc.Lock()
+	defer sc.Unlock()
+
+	if sc.cacheTTL <= 0 {
+		return Addr{}, false
+	}
+	r, ok := sc.masters[name]
+	if !ok || r.invalid || time.Since(r.resolved) >= sc.cacheTTL {
+		return Addr{}, false
+	}
+	return r.addr, true
+}
+
+// cacheMasterAddr records successfully resolved master address. Addresses
+// are recorded even if caching is disabled to be reported as last known.
+func (sc *Client) cacheMasterAddr(name string, addr Addr) {
+	sc.Lock()
+	defer sc.Unlock()
+
+	if sc.masters == nil {
+		sc.masters = make(map[string]resolvedAddr)
+	}
+	sc.masters[name] = resolvedAddr{addr: addr, resolved: time.Now()}
+}
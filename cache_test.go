@@ -0,0 +1,166 @@
+package sentinel
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const getMaster = "SENTINEL get-master-addr-by-name mymaster"
+
+func TestCachedConcurrentDials(t *testing.T) {
+	s, _ := newFakeMaster(t)
+	conf := testConfig(s.addr())
+	conf.MasterCacheTTL = time.Minute
+	p, err := NewPool(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	ctx := context.Background()
+	c, err := p.DialContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := p.DialContext(ctx)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			c.Close()
+		}()
+	}
+	wg.Wait()
+
+	if n := s.calls(getMaster); n != 1 {
+		t.Errorf("sentinel queried %d times, want 1", n)
+	}
+}
+
+func TestCacheInvalidatedOnRoleFailure(t *testing.T) {
+	var demoted int32
+	master := newFake(t, func(args []string) string {
+		if strings.EqualFold(args[0], "ROLE") {
+			if atomic.LoadInt32(&demoted) == 1 {
+				return replicaRole
+			}
+			return masterRole
+		}
+		return "+OK\r\n"
+	})
+	s := newFake(t, pointTo(master.addr()))
+	conf := testConfig(s.addr())
+	conf.MasterCacheTTL = time.Minute
+	p, err := NewPool(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		c, err := p.DialContext(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Close()
+	}
+	if n := s.calls(getMaster); n != 1 {
+		t.Fatalf("sentinel queried %d times, want 1", n)
+	}
+
+	atomic.StoreInt32(&demoted, 1)
+	if c, err := p.DialContext(ctx); err == nil {
+		c.Close()
+		t.Fatal("dialed demoted master")
+	}
+	atomic.StoreInt32(&demoted, 0)
+	c, err := p.DialContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if n := s.calls(getMaster); n != 2 {
+		t.Errorf("sentinel queried %d times after failed role check, want 2", n)
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	s := newFake(t, basicHandler)
+	c := NewClientWithOptions([]string{s.addr()}, WithMasterCache(50*time.Millisecond))
+	defer c.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.MasterAddress("mymaster"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := s.calls(getMaster); n != 1 {
+		t.Fatalf("sentinel queried %d times, want 1", n)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, err := c.MasterAddress("mymaster"); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.calls(getMaster); n != 2 {
+		t.Errorf("sentinel queried %d times after ttl, want 2", n)
+	}
+
+	c.Invalidate("mymaster")
+	if _, err := c.MasterAddress("mymaster"); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.calls(getMaster); n != 3 {
+		t.Errorf("sentinel queried %d times after Invalidate, want 3", n)
+	}
+}
+
+func TestLastKnownMasterAddress(t *testing.T) {
+	var down int32
+	s := newFake(t, func(args []string) string {
+		if strings.EqualFold(args[0], "SENTINEL") && atomic.LoadInt32(&down) == 1 {
+			return "-ERR unavailable\r\n"
+		}
+		return basicHandler(args)
+	})
+	c := NewClient([]string{s.addr()})
+	defer c.Close()
+
+	if _, _, ok := c.LastKnownMasterAddress("mymaster"); ok {
+		t.Fatal("LastKnownMasterAddress() reported master never resolved")
+	}
+	before := time.Now()
+	if _, err := c.MasterAddress("mymaster"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The last known address outlives invalidation and failed lookups.
+	c.Invalidate("mymaster")
+	atomic.StoreInt32(&down, 1)
+	if _, err := c.MasterAddress("mymaster"); err == nil {
+		t.Fatal("MasterAddress() succeeded with sentinel failing")
+	}
+	addr, resolved, ok := c.LastKnownMasterAddress("mymaster")
+	if !ok || addr != "[fd00::1]:6379" {
+		t.Fatalf("LastKnownMasterAddress() = %q, %v, want [fd00::1]:6379", addr, ok)
+	}
+	if resolved.Before(before) || resolved.After(time.Now()) {
+		t.Errorf("resolved at %v, want time of the successful lookup", resolved)
+	}
+	calls := s.calls(getMaster)
+	c.LastKnownMasterAddress("mymaster")
+	if n := s.calls(getMaster); n != calls {
+		t.Errorf("LastKnownMasterAddress() queried sentinel")
+	}
+}
@@ -0,0 +1,453 @@
+package sentinel
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Pool defaults used when the respective Config.Pool fields are not set.
+const (
+	defaultPoolMaxIdle     = 10
+	defaultPoolIdleTimeout = 240 * time.Second
+)
+
+// Recommended timeouts set by DefaultConfig. Sentinel timeouts are kept short
+// as per redis-sentinel client guidelines, so an unresponsive sentinel is
+// quickly skipped in favour of the next one. DefaultKeepAlive is the TCP
+// keep-alive period of both sentinel and redis connections.
+const (
+	DefaultSentinelTimeout = 300 * time.Millisecond
+	DefaultRedisTimeout    = 5 * time.Second
+	DefaultKeepAlive       = 30 * time.Second
+)
+
+// DefaultConfig returns Config for the named master monitored by the
+// sentinels with the recommended timeouts set. It can be adjusted before
+// passing to NewPool.
+func DefaultConfig(master string, sentinels ...string) Config {
+	conf := timeoutsConfig(master, sentinels...)
+	conf.SentinelKeepAlive = DefaultKeepAlive
+	conf.Pool.TestOnBorrowAfter = time.Second
+	return conf
+}
+
+// timeoutsConfig returns Config for the named master monitored by the
+// sentinels with only the recommended timeouts and the keep-alive of redis
+// connections set.
+func timeoutsConfig(master string, sentinels ...string) Config {
+	conf := Config{
+		Master:    master,
+		Sentinels: sentinels,
+	}
+	conf.SentinelTimeouts.Connect = DefaultSentinelTimeout
+	conf.SentinelTimeouts.Read = DefaultSentinelTimeout
+	conf.SentinelTimeouts.Write = DefaultSentinelTimeout
+	conf.RedisTimeouts.Connect = DefaultRedisTimeout
+	conf.RedisTimeouts.Read = DefaultRedisTimeout
+	conf.RedisTimeouts.Write = DefaultRedisTimeout
+	conf.RedisKeepAlive = DefaultKeepAlive
+	return conf
+}
+
+// NoTimeout disables read or write timeout of redis connections when set in
+// Config.RedisTimeouts. Set as Config.SentinelKeepAlive or RedisKeepAlive it
+// disables TCP keep-alive probes, the same as zero.
+const NoTimeout time.Duration = -1
+
+// ConnCheck selects how connections handed out by the pools are verified.
+type ConnCheck string
+
+// Connection checks. CheckRole is used by default.
+const (
+	// CheckRole verifies the server has the expected role with ROLE.
+	CheckRole ConnCheck = "role"
+	// CheckPing verifies the server responds to PING, for servers with
+	// ROLE command renamed or disabled. Server role is not verified.
+	CheckPing ConnCheck = "ping"
+	// CheckNone disables the check.
+	CheckNone ConnCheck = "none"
+)
+
+// Config validation errors, returned wrapped in *ConfigError.
+var (
+	ErrMasterNotSet         = errors.New("master is not set")
+	ErrNoMasters            = errors.New("no masters given")
+	ErrEmptyMasterName      = errors.New("master name is empty")
+	ErrSentinelsNotSet      = errors.New("sentinel array is not set")
+	ErrTimeoutNotSet        = errors.New("timeout is not set")
+	ErrNegativeValue        = errors.New("value is negative")
+	ErrWaitWithoutMaxActive = errors.New("pool wait requires max active to be set")
+	ErrInvalidClientName    = errors.New("client name must not contain spaces")
+	ErrInvalidConnCheck     = errors.New("unknown connection check")
+	ErrInvalidAddress       = errors.New("invalid address")
+	ErrDuplicateAddress     = errors.New("duplicate address")
+	ErrInvalidHostnameMode  = errors.New("unknown hostname mode")
+	ErrInvalidStickiness    = errors.New("unknown stickiness policy")
+)
+
+// ConfigError describes a single invalid Config field.
+type ConfigError struct {
+	// Field is the path of the invalid field, e.g. "SentinelTimeouts.Read".
+	Field string
+	Err   error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Err)
+}
+
+// Unwrap returns the validation error.
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// validateConfig checks the Config fields and returns all the problems found
+// joined into a single error. Each of them is *ConfigError.
+func validateConfig(conf Config) error {
+	return checkConfig(conf, checkMaster(conf.Master), true)
+}
+
+// validatePoolConfig checks the Config fields used by pools sharing an
+// existing sentinel Client, sentinel configuration is ignored.
+func validatePoolConfig(conf Config) error {
+	return checkConfig(conf, checkMaster(conf.Master), false)
+}
+
+// validatePoolsConfig checks the Config used by NewPools. Master names are
+// checked in place of conf.Master, which is ignored.
+func validatePoolsConfig(conf Config, masters []string) error {
+	var errs []error
+	if len(masters) == 0 {
+		errs = append(errs, &ConfigError{Field: "masters", Err: ErrNoMasters})
+	}
+	for i, name := range masters {
+		if name == "" {
+			errs = append(errs, &ConfigError{Field: fmt.Sprintf("masters[%d]", i), Err: ErrEmptyMasterName})
+		}
+	}
+	return checkConfig(conf, errs, true)
+}
+
+func checkMaster(master string) []error {
+	if master == "" {
+		return []error{&ConfigError{Field: "Master", Err: ErrMasterNotSet}}
+	}
+	return nil
+}
+
+// checkConfig appends problems found in conf to errs and returns them all
+// joined. Sentinel fields are checked only if sentinels is set.
+func checkConfig(conf Config, errs []error, sentinels bool) error {
+	invalid := func(field string, err error) {
+		errs = append(errs, &ConfigError{Field: field, Err: err})
+	}
+
+	if sentinels {
+		if len(conf.Sentinels) == 0 {
+			invalid("Sentinels", ErrSentinelsNotSet)
+		}
+		seen := make(map[string]bool, len(conf.Sentinels))
+		for i, addr := range conf.Sentinels {
+			field := fmt.Sprintf("Sentinels[%d]", i)
+			norm, err := normalizeAddr(addr)
+			if err != nil {
+				invalid(field, err)
+				continue
+			}
+			if seen[norm] {
+				invalid(field, fmt.Errorf("%w %q", ErrDuplicateAddress, addr))
+			}
+			seen[norm] = true
+		}
+		if conf.SentinelTimeouts.Connect == 0 {
+			invalid("SentinelTimeouts.Connect", ErrTimeoutNotSet)
+		}
+		if conf.SentinelTimeouts.Read == 0 {
+			invalid("SentinelTimeouts.Read", ErrTimeoutNotSet)
+		}
+		if conf.SentinelTimeouts.Write == 0 {
+			invalid("SentinelTimeouts.Write", ErrTimeoutNotSet)
+		}
+		if conf.SentinelKeepAlive < 0 && conf.SentinelKeepAlive != NoTimeout {
+			invalid("SentinelKeepAlive", ErrNegativeValue)
+		}
+		if strings.ContainsAny(conf.SentinelClientName, " \t\r\n") {
+			invalid("SentinelClientName", ErrInvalidClientName)
+		}
+		if conf.MasterCacheTTL < 0 {
+			invalid("MasterCacheTTL", ErrNegativeValue)
+		}
+		if conf.SentinelConnMaxAge < 0 {
+			invalid("SentinelConnMaxAge", ErrNegativeValue)
+		}
+		if conf.SentinelReresolve < 0 {
+			invalid("SentinelReresolve", ErrNegativeValue)
+		}
+		if conf.SentinelDiscovery.Interval < 0 {
+			invalid("SentinelDiscovery.Interval", ErrNegativeValue)
+		}
+		if conf.SentinelDiscovery.DropAfter < 0 {
+			invalid("SentinelDiscovery.DropAfter", ErrNegativeValue)
+		}
+		if conf.SRVRefresh < 0 {
+			invalid("SRVRefresh", ErrNegativeValue)
+		}
+		switch conf.HostnameMode {
+		case "", HostnameKeep, HostnameResolve, HostnameReject:
+		default:
+			invalid("HostnameMode", ErrInvalidHostnameMode)
+		}
+		switch conf.SentinelStickiness {
+		case "", StickinessSticky, StickinessPreferFirst, StickinessRoundRobin:
+		default:
+			invalid("SentinelStickiness", ErrInvalidStickiness)
+		}
+	}
+	if conf.RedisTimeouts.Connect == 0 {
+		invalid("RedisTimeouts.Connect", ErrTimeoutNotSet)
+	}
+	if conf.RedisTimeouts.Read < 0 && conf.RedisTimeouts.Read != NoTimeout {
+		invalid("RedisTimeouts.Read", ErrNegativeValue)
+	}
+	if conf.RedisTimeouts.Write < 0 && conf.RedisTimeouts.Write != NoTimeout {
+		invalid("RedisTimeouts.Write", ErrNegativeValue)
+	}
+	if conf.RedisKeepAlive < 0 && conf.RedisKeepAlive != NoTimeout {
+		invalid("RedisKeepAlive", ErrNegativeValue)
+	}
+	if strings.ContainsAny(conf.ClientName, " \t\r\n") {
+		invalid("ClientName", ErrInvalidClientName)
+	}
+	if conf.RedisDB < 0 {
+		invalid("RedisDB", ErrNegativeValue)
+	}
+	if conf.MinReplicas < 0 {
+		invalid("MinReplicas", ErrNegativeValue)
+	}
+	if conf.MaxReplicaLag < 0 {
+		invalid("MaxReplicaLag", ErrNegativeValue)
+	}
+	if conf.Pool.MaxIdle < 0 {
+		invalid("Pool.MaxIdle", ErrNegativeValue)
+	}
+	if conf.Pool.MaxActive < 0 {
+		invalid("Pool.MaxActive", ErrNegativeValue)
+	}
+	if conf.Pool.IdleTimeout < 0 {
+		invalid("Pool.IdleTimeout", ErrNegativeValue)
+	}
+	if conf.Pool.MaxConnLifetime < 0 {
+		invalid("Pool.MaxConnLifetime", ErrNegativeValue)
+	}
+	if conf.Pool.TestOnBorrowAfter < 0 {
+		invalid("Pool.TestOnBorrowAfter", ErrNegativeValue)
+	}
+	if !validConnCheck(conf.Pool.BorrowCheck) {
+		invalid("Pool.BorrowCheck", ErrInvalidConnCheck)
+	}
+	if !validConnCheck(conf.Pool.DialCheck) {
+		invalid("Pool.DialCheck", ErrInvalidConnCheck)
+	}
+	if conf.Pool.Wait && conf.Pool.MaxActive == 0 {
+		invalid("Pool.Wait", ErrWaitWithoutMaxActive)
+	}
+
+	return errors.Join(errs...)
+}
+
+func (conf Config) poolMaxIdle() int {
+	if conf.Pool.MaxIdle == 0 {
+		return defaultPoolMaxIdle
+	}
+	return conf.Pool.MaxIdle
+}
+
+func (conf Config) poolIdleTimeout() time.Duration {
+	if conf.Pool.IdleTimeout == 0 {
+		return defaultPoolIdleTimeout
+	}
+	return conf.Pool.IdleTimeout
+}
+
+func validConnCheck(check ConnCheck) bool {
+	switch check {
+	case "", CheckRole, CheckPing, CheckNone:
+		return true
+	}
+	return false
+}
+
+func (conf Config) borrowCheck() ConnCheck {
+	if conf.Pool.BorrowCheck == "" {
+		return CheckRole
+	}
+	return conf.Pool.BorrowCheck
+}
+
+func (conf Config) dialCheck() ConnCheck {
+	if conf.Pool.DialCheck == "" {
+		return conf.borrowCheck()
+	}
+	return conf.Pool.DialCheck
+}
+
+// recentlyUsed reports whether connection last used at t can be borrowed
+// without checking it, see Config.Pool.TestOnBorrowAfter.
+func (conf Config) recentlyUsed(t time.Time) bool {
+	return conf.Pool.TestOnBorrowAfter > 0 && time.Since(t) < conf.Pool.TestOnBorrowAfter
+}
+
+// redisDialOptions returns options for dialing redis servers based on Config.
+func (conf Config) redisDialOptions() []redis.DialOption {
+	options := []redis.DialOption{
+		redis.DialConnectTimeout(conf.RedisTimeouts.Connect),
+		dialKeepAlive(conf.RedisKeepAlive),
+	}
+	if conf.RedisTimeouts.Read != NoTimeout {
+		options = append(options, redis.DialReadTimeout(conf.RedisTimeouts.Read))
+	}
+	if conf.RedisTimeouts.Write != NoTimeout {
+		options = append(options, redis.DialWriteTimeout(conf.RedisTimeouts.Write))
+	}
+	if conf.RedisUsername != "" {
+		options = append(options, redis.DialUsername(conf.RedisUsername))
+	}
+	if conf.RedisPassword != "" {
+		options = append(options, redis.DialPassword(conf.RedisPassword))
+	}
+	if conf.RedisUseTLS || conf.RedisTLS != nil {
+		options = append(options, redis.DialUseTLS(true))
+	}
+	if conf.RedisTLS != nil {
+		options = append(options, redis.DialTLSConfig(conf.RedisTLS))
+	}
+	if conf.RedisDB != 0 {
+		options = append(options, redis.DialDatabase(conf.RedisDB))
+	}
+	if conf.RedisDialer != nil {
+		options = append(options, redis.DialContextFunc(conf.RedisDialer))
+	}
+	return append(options, conf.RedisDialOptions...)
+}
+
+// dialKeepAlive returns dial option setting TCP keep-alive period. Zero and
+// NoTimeout disable keep-alive probes instead of keeping the redigo default.
+func dialKeepAlive(d time.Duration) redis.DialOption {
+	if d <= 0 {
+		return redis.DialKeepAlive(NoTimeout)
+	}
+	return redis.DialKeepAlive(d)
+}
+
+// normalizeAddr validates sentinel address and returns it in host:port form,
+// with the default sentinel port appended if missing. IPv6 addresses can be
+// enclosed in brackets. Address can be prefixed with redis://, tls:// or
+// rediss:// scheme, which is kept. Unix domain socket addresses prefixed
+// with unix:// and SRV record names prefixed with srv:// are returned as is.
+func normalizeAddr(addr string) (string, error) {
+	if addr == "" {
+		return "", fmt.Errorf("%w %q: empty", ErrInvalidAddress, addr)
+	}
+	scheme, hostport, ok := strings.Cut(addr, "://")
+	if !ok {
+		scheme, hostport = "", addr
+	}
+	switch scheme {
+	case "unix":
+		if hostport == "" {
+			return "", fmt.Errorf("%w %q: missing socket path", ErrInvalidAddress, addr)
+		}
+		return addr, nil
+	case "srv":
+		if hostport == "" {
+			return "", fmt.Errorf("%w %q: missing record name", ErrInvalidAddress, addr)
+		}
+		return addr, nil
+	case "", "redis", "tls", "rediss":
+	default:
+		return "", fmt.Errorf("%w %q: unknown scheme %q", ErrInvalidAddress, addr, scheme)
+	}
+
+	host, port := hostport, defaultSentinelPort
+	switch {
+	case strings.HasPrefix(hostport, "[") && strings.HasSuffix(hostport, "]"):
+		host = hostport[1 : len(hostport)-1]
+	case net.ParseIP(hostport) != nil, !strings.Contains(hostport, ":"):
+	default:
+		var err error
+		if host, port, err = net.SplitHostPort(hostport); err != nil {
+			var aerr *net.AddrError
+			if errors.As(err, &aerr) {
+				err = errors.New(aerr.Err)
+			}
+			return "", fmt.Errorf("%w %q: %s", ErrInvalidAddress, addr, err)
+		}
+	}
+	if host == "" {
+		return "", fmt.Errorf("%w %q: missing host", ErrInvalidAddress, addr)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+		return "", fmt.Errorf("%w %q: invalid port", ErrInvalidAddress, addr)
+	}
+	if scheme != "" {
+		return scheme + "://" + net.JoinHostPort(host, port), nil
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// normalizeAddrs returns copy of the sentinel addresses normalized with
+// normalizeAddr. Invalid addresses are kept as is.
+func normalizeAddrs(addrs []string) []string {
+	norm := make([]string, len(addrs))
+	for i, addr := range addrs {
+		if a, err := normalizeAddr(addr); err == nil {
+			addr = a
+		}
+		norm[i] = addr
+	}
+	return norm
+}
+
+// translate rewrites address announced by sentinel with AddressTranslator.
+func (conf Config) translate(addr Addr) Addr {
+	if conf.AddressTranslator == nil {
+		return addr
+	}
+	return conf.AddressTranslator(addr)
+}
+
+// translateAddr is like translate for addresses in host:port form. Addresses
+// that can not be parsed are returned as is.
+func (conf Config) translateAddr(addr string) string {
+	if conf.AddressTranslator == nil {
+		return addr
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return addr
+	}
+	return conf.translate(Addr{Host: host, Port: n}).String()
+}
+
+// dedupAddrs returns addresses with duplicates removed, preserving order.
+func dedupAddrs(addrs []string) []string {
+	seen := make(map[string]bool, len(addrs))
+	dedup := addrs[:0]
+	for _, addr := range addrs {
+		if !seen[addr] {
+			seen[addr] = true
+			dedup = append(dedup, addr)
+		}
+	}
+	return dedup
+}
@@ -0,0 +1,120 @@
+package sentinel
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValidateKeepAlive(t *testing.T) {
+	conf := testConfig("127.0.0.1:26379")
+	conf.SentinelKeepAlive, conf.RedisKeepAlive = NoTimeout, NoTimeout
+	if err := validateConfig(conf); err != nil {
+		t.Fatalf("NoTimeout keep-alive rejected: %v", err)
+	}
+	conf.RedisKeepAlive = -5
+	if err := validateConfig(conf); !errors.Is(err, ErrNegativeValue) {
+		t.Fatalf("validateConfig() = %v, want ErrNegativeValue", err)
+	}
+}
+
+func TestDefaultConfig(t *testing.T) {
+	conf := DefaultConfig("mymaster", "10.0.0.1:26379", "10.0.0.2:26379")
+	want := Config{Master: "mymaster", Sentinels: []string{"10.0.0.1:26379", "10.0.0.2:26379"}}
+	want.SentinelTimeouts.Connect = 300 * time.Millisecond
+	want.SentinelTimeouts.Read = 300 * time.Millisecond
+	want.SentinelTimeouts.Write = 300 * time.Millisecond
+	want.RedisTimeouts.Connect = 5 * time.Second
+	want.RedisTimeouts.Read = 5 * time.Second
+	want.RedisTimeouts.Write = 5 * time.Second
+	want.SentinelKeepAlive = 30 * time.Second
+	want.RedisKeepAlive = 30 * time.Second
+	want.Pool.TestOnBorrowAfter = time.Second
+	if !reflect.DeepEqual(conf, want) {
+		t.Fatalf("DefaultConfig() = %+v, want %+v", conf, want)
+	}
+	if err := validateConfig(conf); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateRequiredFields(t *testing.T) {
+	err := validateConfig(Config{})
+	want := []string{
+		"Master", "Sentinels", "SentinelTimeouts.Connect", "SentinelTimeouts.Read",
+		"SentinelTimeouts.Write", "RedisTimeouts.Connect",
+	}
+	var got []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var cerr *ConfigError
+		if errors.As(e, &cerr) {
+			got = append(got, cerr.Field)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid fields %q, want %q", got, want)
+	}
+}
+
+func TestPoolSizing(t *testing.T) {
+	conf := testConfig("127.0.0.1:26379")
+	p, err := NewPool(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Close()
+	if p.MaxIdle != 10 || p.IdleTimeout != 240*time.Second || p.MaxActive != 0 || p.MaxConnLifetime != 0 {
+		t.Errorf("default pool MaxIdle=%d IdleTimeout=%v MaxActive=%d MaxConnLifetime=%v",
+			p.MaxIdle, p.IdleTimeout, p.MaxActive, p.MaxConnLifetime)
+	}
+
+	conf.Pool.MaxIdle, conf.Pool.MaxActive = 2, 5
+	conf.Pool.IdleTimeout, conf.Pool.MaxConnLifetime = time.Second, time.Minute
+	for _, newPool := range []func(Config) (*Pool, error){NewPool, NewReplicaPool} {
+		p, err := newPool(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.Close()
+		if p.MaxIdle != 2 || p.IdleTimeout != time.Second || p.MaxActive != 5 || p.MaxConnLifetime != time.Minute {
+			t.Errorf("pool MaxIdle=%d IdleTimeout=%v MaxActive=%d MaxConnLifetime=%v",
+				p.MaxIdle, p.IdleTimeout, p.MaxActive, p.MaxConnLifetime)
+		}
+	}
+}
+
+func TestValidatePoolSizing(t *testing.T) {
+	tests := []struct {
+		field string
+		set   func(*Config)
+	}{
+		{"Pool.MaxIdle", func(c *Config) { c.Pool.MaxIdle = -1 }},
+		{"Pool.MaxActive", func(c *Config) { c.Pool.MaxActive = -1 }},
+		{"Pool.IdleTimeout", func(c *Config) { c.Pool.IdleTimeout = -time.Second }},
+		{"Pool.MaxConnLifetime", func(c *Config) { c.Pool.MaxConnLifetime = -time.Second }},
+	}
+	for _, tt := range tests {
+		conf := testConfig("127.0.0.1:26379")
+		tt.set(&conf)
+		err := validateConfig(conf)
+		var cerr *ConfigError
+		if !errors.Is(err, ErrNegativeValue) || !errors.As(err, &cerr) || cerr.Field != tt.field {
+			t.Errorf("%s: validateConfig() error = %v, want ErrNegativeValue", tt.field, err)
+		}
+	}
+}
+
+func TestValidatePoolWait(t *testing.T) {
+	conf := testConfig("127.0.0.1:26379")
+	conf.Pool.Wait = true
+	err := validateConfig(conf)
+	var cerr *ConfigError
+	if !errors.Is(err, ErrWaitWithoutMaxActive) || !errors.As(err, &cerr) || cerr.Field != "Pool.Wait" {
+		t.Errorf("validateConfig() error = %v, want ErrWaitWithoutMaxActive", err)
+	}
+	conf.Pool.MaxActive = 1
+	if err := validateConfig(conf); err != nil {
+		t.Errorf("validateConfig() error = %v", err)
+	}
+}
@@ -0,0 +1,348 @@
+package sentinel
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// defaultMaxConns is the default limit of concurrently used sentinel
+// connections.
+const defaultMaxConns = 3
+
+// sentinelConn is a pooled connection to the sentinel server.
+type sentinelConn struct {
+	redis.Conn
+	addr    string
+	created time.Time
+	// gen is the resolution generation of addr the connection was dialed
+	// in, see WithReresolve.
+	gen uint64
+}
+
+// sentinelCount returns the number of configured sentinels.
+func (sc *Client) sentinelCount() int {
+	sc.Lock()
+	defer sc.Unlock()
+
+	return len(sc.addrs)
+}
+
+// order returns the sentinels in the order they are queried, which is the
+// latency ranking if probed and the configured order otherwise. Client must
+// be locked.
+func (sc *Client) order() []string {
+	if sc.ranked != nil {
+		return sc.ranked
+	}
+	return sc.addrs
+}
+
+// activeSentinel returns address of the sentinel queries are sent to.
+func (sc *Client) activeSentinel() string {
+	sc.Lock()
+	defer sc.Unlock()
+
+	if len(sc.addrs) == 0 {
+		return ""
+	}
+	return sc.order()[sc.activeAddr]
+}
+
+// rotate makes the next sentinel in the list active after a failed query to
+// the sentinel at addr. Nothing is done if other query has already rotated
+// away from addr.
+func (sc *Client) rotate(addr string) {
+	sc.Lock()
+	defer sc.Unlock()
+
+	if order := sc.order(); len(order) > 0 && order[sc.activeAddr] == addr {
+		sc.activeAddr = (sc.activeAddr + 1) % len(order)
+	}
+}
+
+// sentinelsFromActive returns a copy of the sentinel addresses starting with
+// the active sentinel.
+func (sc *Client) sentinelsFromActive() []string {
+	sc.Lock()
+	defer sc.Unlock()
+
+	order := sc.order()
+	addrs := make([]string, 0, len(order))
+	for i := range order {
+		addrs = append(addrs, order[(sc.activeAddr+i)%len(order)])
+	}
+	return addrs
+}
+
+// replaceAddrs replaces the sentinel list keeping the active sentinel if it
+// is still listed. Latency ranking is kept for the sentinels still listed,
+// new ones are ranked last until probed. Connections to the removed sentinels
+// are closed. Client must be locked.
+func (sc *Client) replaceAddrs(addrs []string) {
+	var active string
+	if len(sc.addrs) > 0 {
+		active = sc.order()[sc.activeAddr]
+	}
+	listed := make(map[string]bool, len(addrs))
+	for _, addr := range addrs {
+		listed[addr] = true
+	}
+	for _, addr := range sc.addrs {
+		if !listed[addr] {
+			// Connections in use are closed once returned.
+			if sc.addrGen == nil {
+				sc.addrGen = make(map[string]uint64)
+			}
+			sc.addrGen[addr]++
+		}
+	}
+
+	if sc.ranked != nil {
+		ranked := make([]string, 0, len(addrs))
+		for _, addr := range sc.ranked {
+			if listed[addr] {
+				ranked = append(ranked, addr)
+			}
+		}
+		for _, addr := range addrs {
+			if !contains(ranked, addr) {
+				ranked = append(ranked, addr)
+			}
+		}
+		sc.ranked = ranked
+	}
+
+	// Assign a new slice, as the old one may be in use by the caller.
+	sc.addrs = addrs
+	sc.activeAddr = 0
+	for i, addr := range sc.order() {
+		if addr == active {
+			sc.activeAddr = i
+		}
+	}
+
+	for addr, conns := range sc.idle {
+		if !listed[addr] {
+			for _, c := range conns {
+				c.Close()
+			}
+			delete(sc.idle, addr)
+		}
+	}
+}
+
+// Addrs returns a copy of the addresses of the sentinels used by Client,
+// including the discovered ones.
+func (sc *Client) Addrs() []string {
+	sc.Lock()
+	defer sc.Unlock()
+
+	return append([]string(nil), sc.addrs...)
+}
+
+// ActiveSentinel returns address of the sentinel Client currently talks to,
+// which is tried first by the next query. Empty string is returned if there
+// are no sentinels.
+func (sc *Client) ActiveSentinel() string {
+	return sc.activeSentinel()
+}
+
+// SetAddrs replaces the list of sentinels used by Client. Addresses are
+// normalized and deduplicated, see NewClient. The active sentinel is kept if
+// it is still listed, idle connections to the removed sentinels are closed.
+// Queries in progress finish using the sentinels they were started with.
+// SRV record names not known to Client yet are looked up before SetAddrs
+// returns.
+func (sc *Client) SetAddrs(addrs []string) {
+	static, names := splitSRV(dedupAddrs(normalizeAddrs(addrs)))
+
+	sc.Lock()
+	list := append([]string(nil), static...)
+	resolve := false
+	if d := sc.srvDiscovery(len(names) > 0); d != nil {
+		d.static, d.names = static, names
+		for name := range d.found {
+			if !contains(names, name) {
+				delete(d.found, name)
+			}
+		}
+		for _, name := range names {
+			found, ok := d.found[name]
+			list = append(list, found...)
+			resolve = resolve || !ok
+		}
+	}
+	sc.replaceAddrs(dedupAddrs(list))
+	sc.Unlock()
+
+	if resolve {
+		sc.discover(context.Background())
+	}
+}
+
+// AddSentinel adds sentinel to the end of the list of sentinels used by
+// Client, if not listed already. SRV record name is looked up before
+// AddSentinel returns and the sentinels listed are added.
+func (sc *Client) AddSentinel(addr string) {
+	addr = normalizeAddrs([]string{addr})[0]
+
+	if name := strings.TrimPrefix(addr, srvPrefix); name != addr {
+		sc.Lock()
+		d := sc.srvDiscovery(true)
+		if !contains(d.names, name) {
+			d.names = append(d.names, name)
+		}
+		sc.Unlock()
+		sc.discover(context.Background())
+		return
+	}
+
+	sc.Lock()
+	defer sc.Unlock()
+
+	if sc.srv != nil && !contains(sc.srv.static, addr) {
+		sc.srv.static = append(sc.srv.static, addr)
+	}
+	if contains(sc.addrs, addr) {
+		return
+	}
+	sc.replaceAddrs(append(sc.addrs[:len(sc.addrs):len(sc.addrs)], addr))
+}
+
+// RemoveSentinel removes sentinel from the list of sentinels used by Client
+// and closes its idle connections. Removing SRV record name removes the
+// sentinels discovered by it.
+func (sc *Client) RemoveSentinel(addr string) {
+	addr = normalizeAddrs([]string{addr})[0]
+
+	sc.Lock()
+	defer sc.Unlock()
+
+	if name := strings.TrimPrefix(addr, srvPrefix); name != addr {
+		if sc.srv != nil {
+			sc.srv.names = remove(sc.srv.names, name)
+			sc.mergeDiscovered(nil)
+		}
+		return
+	}
+	if sc.srv != nil {
+		sc.srv.static = remove(sc.srv.static, addr)
+	}
+	sc.replaceAddrs(remove(sc.addrs, addr))
+}
+
+// setActive makes the sentinel at addr active.
+func (sc *Client) setActive(addr string) {
+	sc.Lock()
+	defer sc.Unlock()
+
+	for i, a := range sc.order() {
+		if a == addr {
+			sc.activeAddr = i
+			return
+		}
+	}
+}
+
+// getConn returns connection to the sentinel at addr. If reuse is set, idle
+// connection is returned if there is one, reused is reported as true in that
+// case. Idle connections are kept by sentinel, so queries spread across
+// sentinels reuse them too. It waits for a free connection slot if maximum
+// number of connections is in use.
+func (sc *Client) getConn(ctx context.Context, addr string, reuse bool) (c *sentinelConn, reused bool, err error) {
+	select {
+	case sc.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, false, wrapError("sentinel", ctx.Err())
+	}
+
+	sc.Lock()
+	for reuse && len(sc.idle[addr]) > 0 && c == nil {
+		idle := sc.idle[addr]
+		last := idle[len(idle)-1]
+		sc.idle[addr] = idle[:len(idle)-1]
+		if sc.connUsable(last) {
+			c = last
+		} else {
+			last.Close()
+		}
+	}
+	options, name := sc.options, sc.clientName
+	gen := sc.addrGen[addr]
+	sc.Unlock()
+
+	if c != nil {
+		return c, true, nil
+	}
+	conn, err := dialSentinel(ctx, addr, options, name)
+	if err != nil {
+		<-sc.slots
+		return nil, false, err
+	}
+	return &sentinelConn{Conn: conn, addr: addr, created: time.Now(), gen: gen}, false, nil
+}
+
+// connUsable reports whether connection can be kept for reuse, see
+// WithSentinelConnMaxAge and WithReresolve. Client must be locked.
+func (sc *Client) connUsable(c *sentinelConn) bool {
+	if sc.connMaxAge > 0 && time.Since(c.created) >= sc.connMaxAge {
+		return false
+	}
+	return c.gen == sc.addrGen[c.addr]
+}
+
+// putConn returns connection to the pool after a query that ended with err.
+// Connections that failed with anything but an error reply are closed, as
+// are all connections once Client is closed.
+func (sc *Client) putConn(c *sentinelConn, err error) {
+	defer func() { <-sc.slots }()
+
+	if _, ok := err.(redis.Error); err != nil && !ok {
+		c.Close()
+		return
+	}
+
+	sc.Lock()
+	defer sc.Unlock()
+
+	if sc.closed || len(sc.idle[c.addr]) >= sc.maxConns || !sc.connUsable(c) {
+		c.Close()
+		return
+	}
+	if sc.idle == nil {
+		sc.idle = make(map[string][]*sentinelConn)
+	}
+	sc.idle[c.addr] = append(sc.idle[c.addr], c)
+}
+
+// dialSentinel dials sentinel at addr, which is either host:port optionally
+// prefixed with redis://, tls:// or rediss:// scheme, or path of unix domain
+// socket prefixed with unix://. Scheme prefixes override TLS dial options.
+// Connection is named if name is not empty, see WithClientName.
+func dialSentinel(ctx context.Context, addr string, options []redis.DialOption, name string) (redis.Conn, error) {
+	network, hostport := "tcp", addr
+	if scheme, rest, ok := strings.Cut(addr, "://"); ok {
+		hostport = rest
+		options = options[:len(options):len(options)]
+		switch scheme {
+		case "unix":
+			network = "unix"
+		case "tls", "rediss":
+			options = append(options, redis.DialUseTLS(true))
+		case "redis":
+			options = append(options, redis.DialUseTLS(false))
+		}
+	}
+	c, err := redis.DialContext(ctx, network, hostport, options...)
+	if err != nil {
+		return nil, err
+	}
+	if err := setClientName(c, name); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,273 @@
+package sentinel
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// waitFor polls cond until it holds or a second passes.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCloseInFlightConn(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s := newFake(t, func(args []string) string {
+		if strings.EqualFold(args[0], "SENTINEL") {
+			close(started)
+			<-release
+		}
+		return basicHandler(args)
+	})
+	c := NewClient([]string{s.addr()})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.MasterAddress("mymaster")
+		done <- err
+	}()
+	<-started
+	c.Close()
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	c.Lock()
+	idle := len(c.idle)
+	c.Unlock()
+	if idle != 0 {
+		t.Fatalf("%d idle connections after Close", idle)
+	}
+	waitFor(t, "connection close", func() bool { return s.openConns() == 0 })
+}
+
+func TestIdleConnsPerSentinel(t *testing.T) {
+	// Quorum of two sentinels waits for both replies, so no query is
+	// cancelled midway and every connection is kept.
+	tests := []struct {
+		name      string
+		opt       ClientOption
+		sentinels int
+	}{
+		{"quorum", WithQuorumAgreement(), 2},
+		{"round-robin", WithStickiness(StickinessRoundRobin), 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fakes []*fakeSentinel
+			var addrs []string
+			for i := 0; i < tt.sentinels; i++ {
+				f := newFake(t, basicHandler)
+				fakes = append(fakes, f)
+				addrs = append(addrs, f.addr())
+			}
+			c := NewClientWithOptions(addrs, tt.opt)
+			defer c.Close()
+			for i := 0; i < 6; i++ {
+				if _, err := c.MasterAddress("mymaster"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for i, f := range fakes {
+				if n := f.dialed(); n > 1 {
+					t.Errorf("sentinel %d dialed %d times", i, n)
+				}
+			}
+		})
+	}
+}
+
+func TestSentinelClientName(t *testing.T) {
+	tests := []struct {
+		name         string
+		conf         func(*Config)
+		clientReply  string
+		wantSetNames int
+	}{
+		{"explicit", func(c *Config) { c.SentinelClientName = "svc-sentinel" }, "+OK\r\n", 1},
+		{"not derived", func(c *Config) { c.ClientName = "svc" }, "+OK\r\n", 0},
+		{"unsupported", func(c *Config) { c.SentinelClientName = "svc-sentinel" }, "-ERR unknown command 'CLIENT'\r\n", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFake(t, func(args []string) string {
+				if strings.EqualFold(args[0], "CLIENT") {
+					return tt.clientReply
+				}
+				return basicHandler(args)
+			})
+			conf := testConfig(s.addr())
+			tt.conf(&conf)
+			c := newSentinelClient(conf)
+			defer c.Close()
+			if _, err := c.MasterAddress("mymaster"); err != nil {
+				t.Fatal(err)
+			}
+			if n := s.calls("CLIENT SETNAME svc-sentinel"); n != tt.wantSetNames {
+				t.Errorf("CLIENT SETNAME sent %d times, want %d", n, tt.wantSetNames)
+			}
+			if n := s.calls("CLIENT SETNAME sentinel-go/svc"); n != 0 {
+				t.Errorf("derived sentinel name sent %d times", n)
+			}
+		})
+	}
+}
+
+func TestStaleConnRetried(t *testing.T) {
+	s := newFakeIdle(t, basicHandler, 30*time.Millisecond)
+	c := NewClient([]string{s.addr()})
+	defer c.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.MasterAddress("mymaster"); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(60 * time.Millisecond)
+	}
+	if n := s.dialed(); n != 2 {
+		t.Errorf("sentinel dialed %d times, want 2", n)
+	}
+}
+
+func TestTimeoutNotRetried(t *testing.T) {
+	var mu sync.Mutex
+	lookups := 0
+	slow := newFake(t, func(args []string) string {
+		if strings.EqualFold(args[0], "SENTINEL") {
+			mu.Lock()
+			lookups++
+			n := lookups
+			mu.Unlock()
+			if n > 1 {
+				time.Sleep(200 * time.Millisecond)
+			}
+		}
+		return basicHandler(args)
+	})
+	other := newFake(t, basicHandler)
+	c := NewClient([]string{slow.addr(), other.addr()}, redis.DialReadTimeout(50*time.Millisecond))
+	defer c.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.MasterAddress("mymaster"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := slow.calls("SENTINEL get-master-addr-by-name mymaster"); n != 2 {
+		t.Errorf("slow sentinel queried %d times, want 2", n)
+	}
+	if n := slow.dialed(); n != 1 {
+		t.Errorf("slow sentinel dialed %d times, want 1", n)
+	}
+	if c.ActiveSentinel() != other.addr() {
+		t.Errorf("ActiveSentinel() = %s, want %s", c.ActiveSentinel(), other.addr())
+	}
+}
+
+func TestMaxConns(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	s := newFake(t, func(args []string) string {
+		if strings.EqualFold(args[0], "PING") {
+			mu.Lock()
+			inFlight++
+			if inFlight > peak {
+				peak = inFlight
+			}
+			mu.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}
+		return basicHandler(args)
+	})
+	c := NewClientWithOptions([]string{s.addr()}, WithMaxConns(2))
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Do("PING"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if peak != 2 {
+		t.Errorf("%d queries in flight at once, want 2", peak)
+	}
+	if n := s.dialed(); n != 2 {
+		t.Errorf("sentinel dialed %d times, want 2", n)
+	}
+}
+
+func TestWithClientName(t *testing.T) {
+	s := newFake(t, func(args []string) string {
+		switch strings.ToUpper(args[0]) {
+		case "PSUBSCRIBE":
+			return subscribed
+		case "CLIENT":
+			return "+OK\r\n"
+		}
+		return basicHandler(args)
+	})
+	c := NewClientWithOptions([]string{s.addr()}, WithClientName("svc-sentinel"))
+	defer c.Close()
+
+	if _, err := c.MasterAddress("mymaster"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := c.WatchMaster(ctx, "mymaster"); err != nil {
+		t.Fatal(err)
+	}
+	// Both the query and the subscription connection are named.
+	waitFor(t, "subscription", func() bool { return s.calls("PSUBSCRIBE "+ChannelSwitchMaster) > 0 })
+	if n, dialed := s.calls("CLIENT SETNAME svc-sentinel"), s.dialed(); n != 2 || dialed != 2 {
+		t.Errorf("CLIENT SETNAME sent %d times over %d connections, want 2", n, dialed)
+	}
+}
+
+func TestSentinelConnMaxAge(t *testing.T) {
+	s := newFake(t, basicHandler)
+	c := NewClientWithOptions([]string{s.addr()}, WithSentinelConnMaxAge(50*time.Millisecond))
+	defer c.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.MasterAddress("mymaster"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := s.dialed(); n != 1 {
+		t.Fatalf("dialed %d times before max age, want 1", n)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, err := c.MasterAddress("mymaster"); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.dialed(); n != 2 {
+		t.Errorf("dialed %d times after max age, want 2", n)
+	}
+	waitFor(t, "old connection to close", func() bool { return s.openConns() == 1 })
+}
@@ -0,0 +1,81 @@
+package sentinel
+
+import (
+	"context"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// DialMaster dials a single connection to the current master configured in
+// Config. It is the non-pooled counterpart of NewPool for one-off tasks such
+// as migrations or admin scripts: the master is resolved via the sentinels,
+// dialed with the redis timeouts and its role is checked, failing with the
+// same errors as the pool Dial. The caller must close the connection.
+func DialMaster(conf Config) (redis.Conn, error) {
+	if err := validateConfig(conf); err != nil {
+		return nil, err
+	}
+
+	sentConn := newSentinelClient(conf)
+	defer sentConn.Close()
+
+	return dialMaster(context.Background(), conf, sentConn)
+}
+
+// DialReplica dials a single connection to a healthy replica of the master
+// configured in Config, chosen at random, and checks its role. Replicas
+// reported down or disconnected from the master are skipped.
+// ErrNoReplicaAvailable is returned if there are none, the master is never
+// dialed instead. The caller must close the connection.
+func DialReplica(conf Config) (redis.Conn, error) {
+	if err := validateConfig(conf); err != nil {
+		return nil, err
+	}
+
+	sentConn := newSentinelClient(conf)
+	defer sentConn.Close()
+
+	return dialReplica(context.Background(), conf, sentConn)
+}
+
+// SubscriberConn is a pub/sub connection to the master, see
+// NewSubscriberConn.
+type SubscriberConn struct {
+	redis.PubSubConn
+
+	addr string
+}
+
+// NewSubscriberConn dials the current master configured in Config for use
+// with pub/sub. Unlike pool connections it has no read timeout, so Receive
+// blocks until a message arrives. Use ReceiveWithTimeout together with
+// periodic Ping to detect dead connections. Sentinel does not move pub/sub
+// subscriptions on failover, compare Addr with the master address announced
+// by sentinel to detect a master switch and dial again. Addr is translated
+// with Config AddressTranslator if set.
+func NewSubscriberConn(conf Config) (*SubscriberConn, error) {
+	conf.RedisTimeouts.Read = NoTimeout
+	if err := validateConfig(conf); err != nil {
+		return nil, err
+	}
+
+	sentConn := newSentinelClient(conf)
+	defer sentConn.Close()
+
+	ctx := context.Background()
+	addr, err := sentConn.MasterAddressContext(ctx, conf.Master)
+	if err != nil {
+		return nil, wrapError("sentinel: get master address", err)
+	}
+	addr = conf.translateAddr(addr)
+	c, err := dialRedis(ctx, conf, addr, "master", nil)
+	if err != nil {
+		return nil, err
+	}
+	return &SubscriberConn{PubSubConn: redis.PubSubConn{Conn: c}, addr: addr}, nil
+}
+
+// Addr returns address of the master the connection was dialed to.
+func (c *SubscriberConn) Addr() string {
+	return c.addr
+}
@@ -0,0 +1,214 @@
+package sentinel
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// srvPrefix marks sentinel addresses discovered via DNS SRV records.
+const srvPrefix = "srv://"
+
+// dnsLookupTimeout limits a single DNS lookup.
+const dnsLookupTimeout = 5 * time.Second
+
+// srvDiscovery expands srv:// sentinel addresses into the sentinels listed
+// in DNS SRV records. Its fields are protected by the Client mutex.
+type srvDiscovery struct {
+	names  []string
+	static []string
+	// found are the sentinels last discovered by SRV name.
+	found    map[string][]string
+	interval time.Duration
+	cancel   context.CancelFunc
+	lookup   func(ctx context.Context, name string) ([]*net.SRV, error)
+}
+
+// newSRVDiscovery splits addrs into statically configured sentinels and SRV
+// record names. Nil is returned if there are no SRV names.
+func newSRVDiscovery(addrs []string) *srvDiscovery {
+	d := &srvDiscovery{found: make(map[string][]string)}
+	d.static, d.names = splitSRV(addrs)
+	if len(d.names) == 0 {
+		return nil
+	}
+	return d
+}
+
+// splitSRV splits addrs into sentinel addresses and SRV record names.
+func splitSRV(addrs []string) (static, names []string) {
+	for _, addr := range addrs {
+		if name := strings.TrimPrefix(addr, srvPrefix); name != addr {
+			names = append(names, name)
+		} else {
+			static = append(static, addr)
+		}
+	}
+	return static, names
+}
+
+// startDiscovery resolves SRV sentinel addresses and starts refreshing them
+// if enabled by WithSRVRefresh.
+func (sc *Client) startDiscovery() {
+	d := sc.srv
+	if d == nil {
+		return
+	}
+	sc.discover(context.Background())
+	if d.interval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+	go func() {
+		t := time.NewTicker(d.interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				sc.discover(ctx)
+			}
+		}
+	}()
+}
+
+// lookupSRV looks up sentinels listed in the SRV record name.
+func (sc *Client) lookupSRV(ctx context.Context, d *srvDiscovery, name string) ([]string, error) {
+	lookup := d.lookup
+	if lookup == nil {
+		resolver := sc.resolver
+		if resolver == nil {
+			resolver = net.DefaultResolver
+		}
+		lookup = func(ctx context.Context, name string) ([]*net.SRV, error) {
+			_, srvs, err := resolver.LookupSRV(ctx, "", "", name)
+			return srvs, err
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, dnsLookupTimeout)
+	defer cancel()
+	srvs, err := lookup(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]string, 0, len(srvs))
+	for _, srv := range srvs {
+		host := strings.TrimSuffix(srv.Target, ".")
+		addrs = append(addrs, net.JoinHostPort(host, strconv.Itoa(int(srv.Port))))
+	}
+	return addrs, nil
+}
+
+// discover looks up the SRV records and merges the result into the sentinel
+// list. Names failing to resolve keep the sentinels discovered previously.
+func (sc *Client) discover(ctx context.Context) {
+	sc.Lock()
+	d := sc.srv
+	names := append([]string(nil), d.names...)
+	sc.Unlock()
+
+	found := make(map[string][]string, len(names))
+	for _, name := range names {
+		if addrs, err := sc.lookupSRV(ctx, d, name); err == nil {
+			found[name] = addrs
+		}
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	sc.Lock()
+	defer sc.Unlock()
+
+	sc.mergeDiscovered(found)
+}
+
+// mergeDiscovered updates the sentinels discovered by SRV name. Newly listed
+// sentinels are appended to the sentinel list and the ones no longer listed
+// are removed, unless configured statically or discovered from peers. The
+// order of the other sentinels is kept, as is the removal of discovered
+// sentinels by RemoveSentinel. Client must be locked.
+func (sc *Client) mergeDiscovered(found map[string][]string) {
+	d := sc.srv
+	before := make(map[string]bool)
+	for _, addrs := range d.found {
+		for _, addr := range addrs {
+			before[addr] = true
+		}
+	}
+	listed := make(map[string]bool)
+	for _, name := range d.names {
+		if addrs, ok := found[name]; ok {
+			d.found[name] = addrs
+		}
+		for _, addr := range d.found[name] {
+			listed[addr] = true
+		}
+	}
+	for name := range d.found {
+		if !contains(d.names, name) {
+			delete(d.found, name)
+		}
+	}
+	keep := make(map[string]bool)
+	for _, addr := range d.static {
+		keep[addr] = true
+	}
+	for _, addr := range sc.peerAddrs() {
+		keep[addr] = true
+	}
+
+	addrs := make([]string, 0, len(sc.addrs))
+	known := make(map[string]bool, len(sc.addrs))
+	for _, addr := range sc.addrs {
+		if before[addr] && !listed[addr] && !keep[addr] {
+			continue
+		}
+		known[addr] = true
+		addrs = append(addrs, addr)
+	}
+	for _, name := range d.names {
+		for _, addr := range d.found[name] {
+			if !known[addr] && !before[addr] {
+				known[addr] = true
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+	sc.replaceAddrs(addrs)
+}
+
+// srvDiscovery returns SRV discovery of Client, which is created if needed
+// and create is set. Client must be locked.
+func (sc *Client) srvDiscovery(create bool) *srvDiscovery {
+	if sc.srv == nil && create {
+		sc.srv = &srvDiscovery{found: make(map[string][]string)}
+	}
+	return sc.srv
+}
+
+// remove returns copy of list without s.
+func remove(list []string, s string) []string {
+	kept := make([]string, 0, len(list))
+	for _, v := range list {
+		if v != s {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+// contains reports whether list contains s.
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
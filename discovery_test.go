@@ -0,0 +1,167 @@
+package sentinel
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSRV is SRV resolver serving records set by set.
+type fakeSRV struct {
+	mu      sync.Mutex
+	records map[string][]string
+}
+
+func (f *fakeSRV) set(name string, addrs ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.records == nil {
+		f.records = make(map[string][]string)
+	}
+	f.records[name] = addrs
+}
+
+func (f *fakeSRV) lookup(ctx context.Context, name string) ([]*net.SRV, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	addrs, ok := f.records[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	var srvs []*net.SRV
+	for _, addr := range addrs {
+		host, port, _ := net.SplitHostPort(addr)
+		n, _ := strconv.Atoi(port)
+		srvs = append(srvs, &net.SRV{Target: host + ".", Port: uint16(n)})
+	}
+	return srvs, nil
+}
+
+func newSRVClient(t *testing.T, dns *fakeSRV, addrs ...string) *Client {
+	c := NewClientWithOptions(addrs, func(sc *Client) {
+		if sc.srv != nil {
+			sc.srv.lookup = dns.lookup
+		}
+	})
+	t.Cleanup(c.Close)
+	return c
+}
+
+func checkAddrs(t *testing.T, c *Client, want ...string) {
+	t.Helper()
+	if got := c.Addrs(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Addrs() = %q, want %q", got, want)
+	}
+}
+
+func TestSRVDiscoveryMerge(t *testing.T) {
+	dns := &fakeSRV{}
+	dns.set("_s._tcp.example.com", "10.0.0.2:26379", "10.0.0.3:26379")
+	c := newSRVClient(t, dns, "10.0.0.1", "srv://_s._tcp.example.com")
+	checkAddrs(t, c, "10.0.0.1:26379", "10.0.0.2:26379", "10.0.0.3:26379")
+
+	t.Run("keeps order", func(t *testing.T) {
+		c.Lock()
+		c.addrs = []string{"10.0.0.3:26379", "10.0.0.1:26379", "10.0.0.2:26379"}
+		c.Unlock()
+		c.discover(context.Background())
+		checkAddrs(t, c, "10.0.0.3:26379", "10.0.0.1:26379", "10.0.0.2:26379")
+	})
+	t.Run("keeps user changes", func(t *testing.T) {
+		c.RemoveSentinel("10.0.0.2:26379")
+		c.AddSentinel("10.0.0.9")
+		c.discover(context.Background())
+		checkAddrs(t, c, "10.0.0.3:26379", "10.0.0.1:26379", "10.0.0.9:26379")
+	})
+	t.Run("record changes", func(t *testing.T) {
+		dns.set("_s._tcp.example.com", "10.0.0.2:26379", "10.0.0.4:26379")
+		c.discover(context.Background())
+		checkAddrs(t, c, "10.0.0.1:26379", "10.0.0.9:26379", "10.0.0.4:26379")
+	})
+	t.Run("lookup failure", func(t *testing.T) {
+		dns.mu.Lock()
+		delete(dns.records, "_s._tcp.example.com")
+		dns.mu.Unlock()
+		c.discover(context.Background())
+		checkAddrs(t, c, "10.0.0.1:26379", "10.0.0.9:26379", "10.0.0.4:26379")
+	})
+}
+
+func TestSRVDiscoveryStaticRecord(t *testing.T) {
+	dns := &fakeSRV{}
+	dns.set("_s._tcp.example.com", "10.0.0.1:26379", "10.0.0.2:26379")
+	c := newSRVClient(t, dns, "10.0.0.1", "srv://_s._tcp.example.com")
+	checkAddrs(t, c, "10.0.0.1:26379", "10.0.0.2:26379")
+
+	dns.set("_s._tcp.example.com", "10.0.0.2:26379")
+	c.discover(context.Background())
+	checkAddrs(t, c, "10.0.0.1:26379", "10.0.0.2:26379")
+}
+
+func TestSetAddrsSRV(t *testing.T) {
+	dns := &fakeSRV{}
+	dns.set("_a._tcp.example.com", "10.0.0.2:26379")
+	dns.set("_b._tcp.example.com", "10.0.0.3:26379")
+	c := newSRVClient(t, dns, "srv://_a._tcp.example.com")
+	checkAddrs(t, c, "10.0.0.2:26379")
+
+	c.SetAddrs([]string{"10.0.0.1", "srv://_a._tcp.example.com", "srv://_b._tcp.example.com"})
+	checkAddrs(t, c, "10.0.0.1:26379", "10.0.0.2:26379", "10.0.0.3:26379")
+
+	c.SetAddrs([]string{"10.0.0.5"})
+	c.discover(context.Background())
+	checkAddrs(t, c, "10.0.0.5:26379")
+}
+
+func TestAddRemoveSentinelSRV(t *testing.T) {
+	dns := &fakeSRV{}
+	dns.set("_a._tcp.example.com", "10.0.0.2:26379")
+	dns.set("_b._tcp.example.com", "10.0.0.3:26379", "10.0.0.1:26379")
+	c := newSRVClient(t, dns, "10.0.0.1", "srv://_a._tcp.example.com")
+
+	c.AddSentinel("srv://_b._tcp.example.com")
+	checkAddrs(t, c, "10.0.0.1:26379", "10.0.0.2:26379", "10.0.0.3:26379")
+	for _, addr := range c.Addrs() {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			t.Fatalf("unexpanded sentinel address %q", addr)
+		}
+	}
+
+	c.RemoveSentinel("srv://_b._tcp.example.com")
+	checkAddrs(t, c, "10.0.0.1:26379", "10.0.0.2:26379")
+}
+
+func TestAddSentinelSRVWithoutDiscovery(t *testing.T) {
+	c := NewClient([]string{"10.0.0.1"})
+	defer c.Close()
+	dns := &fakeSRV{}
+	dns.set("_a._tcp.example.com", "10.0.0.2:26379")
+	c.Lock()
+	c.srvDiscovery(true).lookup = dns.lookup
+	c.Unlock()
+
+	c.AddSentinel("srv://_a._tcp.example.com")
+	checkAddrs(t, c, "10.0.0.1:26379", "10.0.0.2:26379")
+}
+
+func TestSRVRefresh(t *testing.T) {
+	dns := &fakeSRV{}
+	dns.set("_s._tcp.example.com", "10.0.0.2:26379")
+	c := NewClientWithOptions([]string{"srv://_s._tcp.example.com"}, WithSRVRefresh(10*time.Millisecond), func(sc *Client) {
+		sc.srv.lookup = dns.lookup
+	})
+	defer c.Close()
+	checkAddrs(t, c, "10.0.0.2:26379")
+
+	dns.set("_s._tcp.example.com", "10.0.0.2:26379", "10.0.0.3:26379")
+	waitFor(t, "refresh to add sentinel", func() bool { return len(c.Addrs()) == 2 })
+	checkAddrs(t, c, "10.0.0.2:26379", "10.0.0.3:26379")
+
+	dns.set("_s._tcp.example.com", "10.0.0.3:26379")
+	waitFor(t, "refresh to remove sentinel", func() bool { return len(c.Addrs()) == 1 })
+	checkAddrs(t, c, "10.0.0.3:26379")
+}
@@ -0,0 +1,252 @@
+package sentinel
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// duration is time.Duration encoded in time.ParseDuration format, such as
+// "300ms". Plain integers are decoded as nanoseconds.
+type duration time.Duration
+
+// redacted replaces non-empty secrets.
+const redacted = "****"
+
+func (d duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *duration) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		*d = duration(n)
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("sentinel: invalid duration %q", s)
+	}
+	*d = duration(v)
+	return nil
+}
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		return d.UnmarshalText([]byte(s))
+	}
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("sentinel: invalid duration %s", data)
+	}
+	*d = duration(n)
+	return nil
+}
+
+// configData is the encoded form of Config, with durations in human readable
+// format. Fields that can not be encoded, such as TLS configuration and dial
+// options, are omitted.
+type configData struct {
+	Master           string   `json:"master" yaml:"master"`
+	Sentinels        []string `json:"sentinels" yaml:"sentinels"`
+	SentinelTimeouts struct {
+		Connect duration `json:"connect" yaml:"connect"`
+		Read    duration `json:"read" yaml:"read"`
+		Write   duration `json:"write" yaml:"write"`
+		Total   duration `json:"total" yaml:"total"`
+	} `json:"sentinel_timeouts" yaml:"sentinel_timeouts"`
+	SentinelKeepAlive  duration `json:"sentinel_keepalive" yaml:"sentinel_keepalive"`
+	SentinelUsername   string   `json:"sentinel_username" yaml:"sentinel_username"`
+	SentinelPassword   string   `json:"sentinel_password" yaml:"sentinel_password"`
+	SentinelClientName string   `json:"sentinel_client_name" yaml:"sentinel_client_name"`
+	RedisTimeouts      struct {
+		Connect duration `json:"connect" yaml:"connect"`
+		Read    duration `json:"read" yaml:"read"`
+		Write   duration `json:"write" yaml:"write"`
+	} `json:"redis_timeouts" yaml:"redis_timeouts"`
+	RedisKeepAlive     duration   `json:"redis_keepalive" yaml:"redis_keepalive"`
+	RedisUsername      string     `json:"redis_username" yaml:"redis_username"`
+	RedisPassword      string     `json:"redis_password" yaml:"redis_password"`
+	RedisUseTLS        bool       `json:"redis_use_tls" yaml:"redis_use_tls"`
+	ClientName         string     `json:"client_name" yaml:"client_name"`
+	RedisDB            int        `json:"redis_db" yaml:"redis_db"`
+	MinReplicas        int        `json:"min_replicas" yaml:"min_replicas"`
+	MaxReplicaLag      duration   `json:"max_replica_lag" yaml:"max_replica_lag"`
+	MasterCacheTTL     duration   `json:"master_cache_ttl" yaml:"master_cache_ttl"`
+	ShuffleSentinels   bool       `json:"shuffle_sentinels" yaml:"shuffle_sentinels"`
+	SentinelStickiness Stickiness `json:"sentinel_stickiness" yaml:"sentinel_stickiness"`
+	MasterDownCheck    bool       `json:"master_down_check" yaml:"master_down_check"`
+	SentinelConnMaxAge duration   `json:"sentinel_conn_max_age" yaml:"sentinel_conn_max_age"`
+	SentinelReresolve  duration   `json:"sentinel_reresolve" yaml:"sentinel_reresolve"`
+	SentinelDiscovery  struct {
+		Interval  duration `json:"interval" yaml:"interval"`
+		DropAfter duration `json:"drop_after" yaml:"drop_after"`
+	} `json:"sentinel_discovery" yaml:"sentinel_discovery"`
+	SRVRefresh   duration     `json:"srv_refresh" yaml:"srv_refresh"`
+	HostnameMode HostnameMode `json:"hostname_mode" yaml:"hostname_mode"`
+	Pool         struct {
+		MaxIdle           int       `json:"max_idle" yaml:"max_idle"`
+		MaxActive         int       `json:"max_active" yaml:"max_active"`
+		IdleTimeout       duration  `json:"idle_timeout" yaml:"idle_timeout"`
+		MaxConnLifetime   duration  `json:"max_conn_lifetime" yaml:"max_conn_lifetime"`
+		Wait              bool      `json:"wait" yaml:"wait"`
+		BorrowCheck       ConnCheck `json:"borrow_check" yaml:"borrow_check"`
+		DialCheck         ConnCheck `json:"dial_check" yaml:"dial_check"`
+		TestOnBorrowAfter duration  `json:"test_on_borrow_after" yaml:"test_on_borrow_after"`
+		CheckLoading      bool      `json:"check_loading" yaml:"check_loading"`
+		CheckWritable     bool      `json:"check_writable" yaml:"check_writable"`
+		WriteProbeKey     string    `json:"write_probe_key" yaml:"write_probe_key"`
+	} `json:"pool" yaml:"pool"`
+}
+
+func (conf Config) data() configData {
+	var d configData
+	d.Master = conf.Master
+	d.Sentinels = conf.Sentinels
+	d.SentinelTimeouts.Connect = duration(conf.SentinelTimeouts.Connect)
+	d.SentinelTimeouts.Read = duration(conf.SentinelTimeouts.Read)
+	d.SentinelTimeouts.Write = duration(conf.SentinelTimeouts.Write)
+	d.SentinelTimeouts.Total = duration(conf.SentinelTimeouts.Total)
+	d.SentinelKeepAlive = duration(conf.SentinelKeepAlive)
+	d.SentinelUsername = conf.SentinelUsername
+	d.SentinelPassword = conf.SentinelPassword
+	d.SentinelClientName = conf.SentinelClientName
+	d.RedisTimeouts.Connect = duration(conf.RedisTimeouts.Connect)
+	d.RedisTimeouts.Read = duration(conf.RedisTimeouts.Read)
+	d.RedisTimeouts.Write = duration(conf.RedisTimeouts.Write)
+	d.RedisKeepAlive = duration(conf.RedisKeepAlive)
+	d.RedisUsername = conf.RedisUsername
+	d.RedisPassword = conf.RedisPassword
+	d.RedisUseTLS = conf.RedisUseTLS
+	d.ClientName = conf.ClientName
+	d.RedisDB = conf.RedisDB
+	d.MinReplicas = conf.MinReplicas
+	d.MaxReplicaLag = duration(conf.MaxReplicaLag)
+	d.MasterCacheTTL = duration(conf.MasterCacheTTL)
+	d.ShuffleSentinels = conf.ShuffleSentinels
+	d.SentinelStickiness = conf.SentinelStickiness
+	d.MasterDownCheck = conf.MasterDownCheck
+	d.SentinelConnMaxAge = duration(conf.SentinelConnMaxAge)
+	d.SentinelReresolve = duration(conf.SentinelReresolve)
+	d.SentinelDiscovery.Interval = duration(conf.SentinelDiscovery.Interval)
+	d.SentinelDiscovery.DropAfter = duration(conf.SentinelDiscovery.DropAfter)
+	d.SRVRefresh = duration(conf.SRVRefresh)
+	d.HostnameMode = conf.HostnameMode
+	d.Pool.MaxIdle = conf.Pool.MaxIdle
+	d.Pool.MaxActive = conf.Pool.MaxActive
+	d.Pool.IdleTimeout = duration(conf.Pool.IdleTimeout)
+	d.Pool.MaxConnLifetime = duration(conf.Pool.MaxConnLifetime)
+	d.Pool.Wait = conf.Pool.Wait
+	d.Pool.BorrowCheck = conf.Pool.BorrowCheck
+	d.Pool.DialCheck = conf.Pool.DialCheck
+	d.Pool.TestOnBorrowAfter = duration(conf.Pool.TestOnBorrowAfter)
+	d.Pool.CheckLoading = conf.Pool.CheckLoading
+	d.Pool.CheckWritable = conf.Pool.CheckWritable
+	d.Pool.WriteProbeKey = conf.Pool.WriteProbeKey
+	return d
+}
+
+// setData sets the encodable Config fields from d.
+func (conf *Config) setData(d configData) {
+	conf.Master = d.Master
+	conf.Sentinels = d.Sentinels
+	conf.SentinelTimeouts.Connect = time.Duration(d.SentinelTimeouts.Connect)
+	conf.SentinelTimeouts.Read = time.Duration(d.SentinelTimeouts.Read)
+	conf.SentinelTimeouts.Write = time.Duration(d.SentinelTimeouts.Write)
+	conf.SentinelTimeouts.Total = time.Duration(d.SentinelTimeouts.Total)
+	conf.SentinelKeepAlive = time.Duration(d.SentinelKeepAlive)
+	conf.SentinelUsername = d.SentinelUsername
+	conf.SentinelPassword = d.SentinelPassword
+	conf.SentinelClientName = d.SentinelClientName
+	conf.RedisTimeouts.Connect = time.Duration(d.RedisTimeouts.Connect)
+	conf.RedisTimeouts.Read = time.Duration(d.RedisTimeouts.Read)
+	conf.RedisTimeouts.Write = time.Duration(d.RedisTimeouts.Write)
+	conf.RedisKeepAlive = time.Duration(d.RedisKeepAlive)
+	conf.RedisUsername = d.RedisUsername
+	conf.RedisPassword = d.RedisPassword
+	conf.RedisUseTLS = d.RedisUseTLS
+	conf.ClientName = d.ClientName
+	conf.RedisDB = d.RedisDB
+	conf.MinReplicas = d.MinReplicas
+	conf.MaxReplicaLag = time.Duration(d.MaxReplicaLag)
+	conf.MasterCacheTTL = time.Duration(d.MasterCacheTTL)
+	conf.ShuffleSentinels = d.ShuffleSentinels
+	conf.SentinelStickiness = d.SentinelStickiness
+	conf.MasterDownCheck = d.MasterDownCheck
+	conf.SentinelConnMaxAge = time.Duration(d.SentinelConnMaxAge)
+	conf.SentinelReresolve = time.Duration(d.SentinelReresolve)
+	conf.SentinelDiscovery.Interval = time.Duration(d.SentinelDiscovery.Interval)
+	conf.SentinelDiscovery.DropAfter = time.Duration(d.SentinelDiscovery.DropAfter)
+	conf.SRVRefresh = time.Duration(d.SRVRefresh)
+	conf.HostnameMode = d.HostnameMode
+	conf.Pool.MaxIdle = d.Pool.MaxIdle
+	conf.Pool.MaxActive = d.Pool.MaxActive
+	conf.Pool.IdleTimeout = time.Duration(d.Pool.IdleTimeout)
+	conf.Pool.MaxConnLifetime = time.Duration(d.Pool.MaxConnLifetime)
+	conf.Pool.Wait = d.Pool.Wait
+	conf.Pool.BorrowCheck = d.Pool.BorrowCheck
+	conf.Pool.DialCheck = d.Pool.DialCheck
+	conf.Pool.TestOnBorrowAfter = time.Duration(d.Pool.TestOnBorrowAfter)
+	conf.Pool.CheckLoading = d.Pool.CheckLoading
+	conf.Pool.CheckWritable = d.Pool.CheckWritable
+	conf.Pool.WriteProbeKey = d.Pool.WriteProbeKey
+}
+
+// redactedData returns the encoded form of Config with passwords replaced.
+func (conf Config) redactedData() configData {
+	d := conf.data()
+	if d.SentinelPassword != "" {
+		d.SentinelPassword = redacted
+	}
+	if d.RedisPassword != "" {
+		d.RedisPassword = redacted
+	}
+	return d
+}
+
+// String formats Config with passwords redacted, so it is safe to log.
+func (conf Config) String() string {
+	return fmt.Sprintf("%+v", conf.redactedData())
+}
+
+// MarshalJSON encodes Config with passwords redacted and durations in
+// time.ParseDuration format. Fields that can not be encoded, such as TLS
+// configuration and dial options, are omitted.
+func (conf Config) MarshalJSON() ([]byte, error) {
+	return json.Marshal(conf.redactedData())
+}
+
+// UnmarshalJSON decodes Config with durations either in time.ParseDuration
+// format, such as "300ms", or as integer nanoseconds. Fields missing in data
+// keep their current values, so Config returned by DefaultConfig can be used
+// as a base.
+func (conf *Config) UnmarshalJSON(data []byte) error {
+	d := conf.data()
+	if err := json.Unmarshal(data, &d); err != nil {
+		return err
+	}
+	conf.setData(d)
+	return nil
+}
+
+// UnmarshalYAML decodes Config the same way as UnmarshalJSON. It implements
+// the unmarshaler interface of gopkg.in/yaml.v2, which is supported by
+// gopkg.in/yaml.v3 as well.
+func (conf *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	d := conf.data()
+	if err := unmarshal(&d); err != nil {
+		return err
+	}
+	conf.setData(d)
+	return nil
+}
@@ -0,0 +1,161 @@
+package sentinel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readFixture(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// checkFixture checks conf decoded from testdata/config.json on top of
+// DefaultConfig.
+func checkFixture(t *testing.T, conf Config) {
+	t.Helper()
+	want := DefaultConfig("mymaster", "10.0.0.1:26379", "10.0.0.2:26379", "10.0.0.3:26379")
+	want.SentinelTimeouts.Connect = 300 * time.Millisecond
+	want.SentinelTimeouts.Read = 250 * time.Millisecond
+	want.SentinelTimeouts.Write = 300 * time.Millisecond
+	want.SentinelTimeouts.Total = time.Second
+	want.SentinelPassword = "sentinel-secret"
+	want.RedisTimeouts.Connect = 5 * time.Second
+	want.RedisTimeouts.Read = NoTimeout
+	want.RedisTimeouts.Write = 5 * time.Second
+	want.RedisKeepAlive = 30 * time.Second
+	want.RedisPassword = "redis-secret"
+	want.RedisDB = 3
+	want.ClientName = "api"
+	want.MasterCacheTTL = 2 * time.Second
+	want.SentinelStickiness = StickinessSticky
+	want.Pool.MaxIdle = 5
+	want.Pool.MaxActive = 10
+	want.Pool.Wait = true
+	want.Pool.IdleTimeout = time.Minute
+	want.Pool.MaxConnLifetime = time.Hour
+	want.Pool.TestOnBorrowAfter = 90 * time.Second
+
+	if conf.String() != want.String() || conf.SentinelPassword != want.SentinelPassword || conf.RedisPassword != want.RedisPassword {
+		t.Errorf("decoded %v\nwant %v", conf, want)
+	}
+	if err := validateConfig(conf); err != nil {
+		t.Errorf("decoded config is invalid: %v", err)
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	conf := DefaultConfig("")
+	if err := json.Unmarshal(readFixture(t), &conf); err != nil {
+		t.Fatal(err)
+	}
+	checkFixture(t, conf)
+}
+
+func TestUnmarshalYAML(t *testing.T) {
+	// YAML decoders call UnmarshalYAML with a function decoding the
+	// document into the given value, JSON is a subset of YAML.
+	data := readFixture(t)
+	conf := DefaultConfig("")
+	err := conf.UnmarshalYAML(func(v interface{}) error {
+		return json.Unmarshal(data, v)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkFixture(t, conf)
+}
+
+func TestUnmarshalInvalidDuration(t *testing.T) {
+	for _, data := range []string{
+		`{"redis_timeouts": {"read": "soon"}}`,
+		`{"pool": {"idle_timeout": "5 minutes"}}`,
+		`{"master_cache_ttl": true}`,
+	} {
+		var conf Config
+		if err := json.Unmarshal([]byte(data), &conf); err == nil {
+			t.Errorf("%s: decoded %v", data, conf)
+		}
+	}
+}
+
+func TestMarshalJSONRoundTrip(t *testing.T) {
+	conf := DefaultConfig("")
+	if err := json.Unmarshal(readFixture(t), &conf); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Config
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != conf.String() {
+		t.Errorf("round trip %v\nwant %v", got, conf)
+	}
+}
+
+func TestConfigRedaction(t *testing.T) {
+	conf := DefaultConfig("mymaster", "10.0.0.1:26379")
+	conf.SentinelPassword, conf.RedisPassword = "s3cret-sentinel", "s3cret-redis"
+
+	out := []string{
+		conf.String(),
+		fmt.Sprint(conf),
+		fmt.Sprintf("%v", &conf),
+		fmt.Sprintf("%+v", conf),
+		conf.URL(),
+	}
+	for _, v := range []interface{}{conf, &conf} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, string(data))
+	}
+	for _, s := range out {
+		if strings.Contains(s, "s3cret") {
+			t.Errorf("secret leaked: %s", s)
+		}
+		if !strings.Contains(s, redacted) || !strings.Contains(s, "mymaster") {
+			t.Errorf("redacted output %s lacks password placeholder or master name", s)
+		}
+	}
+}
+
+func TestAuthErrorRedaction(t *testing.T) {
+	s := newFake(t, func(args []string) string {
+		if strings.EqualFold(args[0], "AUTH") {
+			return "-WRONGPASS invalid username-password pair or user is disabled.\r\n"
+		}
+		return basicHandler(args)
+	})
+	conf := testConfig(s.addr())
+	conf.SentinelPassword = "s3cret-sentinel"
+	p, err := NewPool(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	_, err = p.DialContext(context.Background())
+	var aerr *AuthError
+	if !errors.As(err, &aerr) {
+		t.Fatalf("DialContext() error = %v, want *AuthError", err)
+	}
+	if strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("secret leaked: %v", err)
+	}
+}
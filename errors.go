@@ -0,0 +1,338 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// TimeoutError is returned when a lookup exceeds the total timeout configured
+// for Client before any of the sentinels replied.
+type TimeoutError struct {
+	// Attempts is the number of sentinels tried before giving up.
+	Attempts int
+	// Sentinels is the number of configured sentinels.
+	Sentinels int
+	// Total is the total timeout configured for Client.
+	Total time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("sentinel: timed out after %s, tried %d of %d sentinels", e.Total, e.Attempts, e.Sentinels)
+}
+
+// Timeout reports the error as a timeout, it is always true.
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+// Temporary reports the error as temporary, it is always true.
+func (e *TimeoutError) Temporary() bool {
+	return true
+}
+
+// Unwrap returns context.DeadlineExceeded.
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// ErrNoSentinelsConfigured is returned for queries of Client created without
+// any sentinel addresses.
+var ErrNoSentinelsConfigured = errors.New("sentinel: no sentinels configured")
+
+// ErrNoSentinelReachable is returned when none of the configured sentinels
+// could be queried.
+var ErrNoSentinelReachable = errors.New("sentinel: no sentinel reachable")
+
+// MasterUnknownError is returned by master address lookups when the sentinel
+// replies it does not monitor a master with the requested name. It matches
+// ErrMasterUnknown.
+type MasterUnknownError struct {
+	Name string
+	// Sentinel is the address of the sentinel that replied.
+	Sentinel string
+}
+
+func (e *MasterUnknownError) Error() string {
+	if e.Sentinel == "" {
+		return fmt.Sprintf("sentinel: master %q unknown to sentinel", e.Name)
+	}
+	return fmt.Sprintf("sentinel: master %q unknown to sentinel %s", e.Name, e.Sentinel)
+}
+
+// Unwrap returns ErrMasterUnknown.
+func (e *MasterUnknownError) Unwrap() error {
+	return ErrMasterUnknown
+}
+
+// ErrMasterDown is matched by *MasterDownError.
+var ErrMasterDown = errors.New("sentinel: master is down")
+
+// MasterDownError is returned by master address lookups when the sentinel
+// flags the master as down or a failover of the master is in progress, see
+// WithMasterDownCheck. It matches ErrMasterDown.
+type MasterDownError struct {
+	Name string
+	Addr Addr
+	// Flags are the master flags reported by sentinel, e.g. "s_down",
+	// "o_down" or "failover_in_progress".
+	Flags []string
+	// Sentinel is the address of the sentinel that replied.
+	Sentinel string
+}
+
+func (e *MasterDownError) Error() string {
+	return fmt.Sprintf("sentinel: master %q at %s is flagged %s by sentinel %s", e.Name, e.Addr, strings.Join(e.Flags, ","), e.Sentinel)
+}
+
+// Is reports if target is ErrMasterDown.
+func (e *MasterDownError) Is(target error) bool {
+	return target == ErrMasterDown
+}
+
+// AttemptError is an error of a single query attempt on the sentinel.
+type AttemptError struct {
+	Sentinel string
+	Err      error
+}
+
+func (e AttemptError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Sentinel, e.Err)
+}
+
+// Unwrap returns the attempt error.
+func (e AttemptError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout reports if the attempt timed out.
+func (e AttemptError) Timeout() bool {
+	return isTimeout(e.Err)
+}
+
+// Temporary reports if the attempt failed with a temporary error.
+func (e AttemptError) Temporary() bool {
+	return isTemporary(e.Err)
+}
+
+// NoSentinelReachableError is returned when queries to all the sentinels
+// fail. It matches ErrNoSentinelReachable.
+type NoSentinelReachableError struct {
+	// Name is the master looked up, empty for other queries.
+	Name string
+	// Sentinel is the address of the last sentinel tried.
+	Sentinel string
+	// Err is the error of the last attempt.
+	Err error
+
+	attempts []AttemptError
+}
+
+func (e *NoSentinelReachableError) Error() string {
+	msgs := make([]string, 0, len(e.attempts))
+	for _, a := range e.attempts {
+		msgs = append(msgs, a.Error())
+	}
+	if len(msgs) == 0 {
+		msgs = append(msgs, AttemptError{Sentinel: e.Sentinel, Err: e.Err}.Error())
+	}
+	if e.Name != "" {
+		return fmt.Sprintf("sentinel: no sentinel reachable for master %q (last tried %s): %s", e.Name, e.Sentinel, strings.Join(msgs, "; "))
+	}
+	return fmt.Sprintf("sentinel: no sentinel reachable (last tried %s): %s", e.Sentinel, strings.Join(msgs, "; "))
+}
+
+// Attempts returns errors of all the failed attempts in the order the
+// sentinels were tried.
+func (e *NoSentinelReachableError) Attempts() []AttemptError {
+	return append([]AttemptError(nil), e.attempts...)
+}
+
+// Timeout reports if all the attempts timed out.
+func (e *NoSentinelReachableError) Timeout() bool {
+	return e.all(isTimeout)
+}
+
+// Temporary reports if all the attempts failed with temporary errors.
+func (e *NoSentinelReachableError) Temporary() bool {
+	return e.all(isTemporary)
+}
+
+func (e *NoSentinelReachableError) all(check func(error) bool) bool {
+	if len(e.attempts) == 0 {
+		return check(e.Err)
+	}
+	for _, a := range e.attempts {
+		if !check(a.Err) {
+			return false
+		}
+	}
+	return true
+}
+
+// Is reports if target is ErrNoSentinelReachable.
+func (e *NoSentinelReachableError) Is(target error) bool {
+	return target == ErrNoSentinelReachable
+}
+
+// Unwrap returns the error of the last attempt.
+func (e *NoSentinelReachableError) Unwrap() error {
+	return e.Err
+}
+
+// unreachable wraps errors of the failed attempts into
+// *NoSentinelReachableError. It unwraps to the error of the last attempt, so
+// error reply of the last sentinel tried can be checked with errors.As.
+func unreachable(attempts []AttemptError) error {
+	if len(attempts) == 0 {
+		return ErrNoSentinelsConfigured
+	}
+	last := attempts[len(attempts)-1]
+	return &NoSentinelReachableError{Sentinel: last.Sentinel, Err: last.Err, attempts: attempts}
+}
+
+// parseMasterAddrReply parses reply to get-master-addr-by-name of the named
+// master received from the sentinel at addr.
+func parseMasterAddrReply(name, addr string, reply interface{}) (Addr, error) {
+	if reply == nil {
+		return Addr{}, &MasterUnknownError{Name: name, Sentinel: addr}
+	}
+	res, err := redis.Strings(reply, nil)
+	if err != nil {
+		return Addr{}, err
+	}
+	return parseAddr(res)
+}
+
+// parseMasterReply parses reply to SENTINEL master of the named master
+// received from the sentinel at addr. *MasterDownError is returned if the
+// master is flagged down or being failed over.
+func parseMasterReply(name, addr string, reply interface{}) (Addr, error) {
+	m, err := redis.StringMap(reply, nil)
+	if err != nil {
+		return Addr{}, err
+	}
+	info, err := parseMasterInfo(m)
+	if err != nil {
+		return Addr{}, err
+	}
+	if info.IP == "" {
+		return Addr{}, &AddrError{Reason: "missing master ip"}
+	}
+	for _, f := range info.Flags {
+		switch f {
+		case "s_down", "o_down", "failover_in_progress":
+			return Addr{}, &MasterDownError{Name: name, Addr: info.Addr(), Flags: info.Flags, Sentinel: addr}
+		}
+	}
+	return info.Addr(), nil
+}
+
+// RoleError is returned by TestRole when the server role does not match the
+// expected one.
+type RoleError struct {
+	Expected string
+	Actual   string
+	// Addr is the address of the checked server, if known.
+	Addr string
+}
+
+func (e *RoleError) Error() string {
+	if e.Addr != "" {
+		return fmt.Sprintf("role check failed: %s is %s, expected %s", e.Addr, e.Actual, e.Expected)
+	}
+	return fmt.Sprintf("role check failed: server is %s, expected %s", e.Actual, e.Expected)
+}
+
+// causeError annotates the underlying error while preserving its timeout and
+// temporary status, see net.Error.
+type causeError struct {
+	msg string
+	err error
+}
+
+// wrapError returns err annotated with msg.
+func wrapError(msg string, err error) error {
+	return &causeError{msg: msg, err: err}
+}
+
+func (e *causeError) Error() string {
+	return e.msg + ": " + e.err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *causeError) Unwrap() error {
+	return e.err
+}
+
+// Timeout reports if the underlying error is a timeout.
+func (e *causeError) Timeout() bool {
+	return isTimeout(e.err)
+}
+
+// Temporary reports if the underlying error is temporary.
+func (e *causeError) Temporary() bool {
+	return isTemporary(e.err)
+}
+
+// isTimeout reports if err or any error it wraps reports being a timeout.
+func isTimeout(err error) bool {
+	var t interface{ Timeout() bool }
+	return errors.As(err, &t) && t.Timeout()
+}
+
+// isStaleConn reports if err is a failure of connection closed by the peer,
+// as happens to idle connections closed by the sentinel timeout. Timeouts are
+// not, as the sentinel might be just slow.
+func isStaleConn(err error) bool {
+	if isTimeout(err) {
+		return false
+	}
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+}
+
+// isTemporary reports if err or any error it wraps reports being temporary.
+func isTemporary(err error) bool {
+	var t interface{ Temporary() bool }
+	return errors.As(err, &t) && t.Temporary()
+}
+
+// AuthError is returned when the server rejects the connection credentials
+// or requires authentication and none was configured.
+type AuthError struct {
+	Addr string
+	Err  error
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("sentinel: authentication to %s failed: %s", e.Addr, e.Err)
+}
+
+// Unwrap returns the error reply of the server.
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// isAuthError checks if err is an error reply rejecting the connection
+// credentials. NOPERM replies are not, as they reject a command the user is
+// not authorized to run, not the credentials.
+func isAuthError(err error) bool {
+	rerr, ok := err.(redis.Error)
+	if !ok {
+		return false
+	}
+	msg := string(rerr)
+	for _, prefix := range []string{"NOAUTH", "WRONGPASS", "ERR invalid password", "ERR AUTH", "ERR Client sent AUTH"} {
+		if strings.HasPrefix(msg, prefix) {
+			return true
+		}
+	}
+	return false
+}
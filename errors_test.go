@@ -0,0 +1,231 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestUnreachableAggregatesErrorReplies(t *testing.T) {
+	down := newFake(t, func(args []string) string { return closeAfter })
+	busy := newFake(t, func(args []string) string {
+		if strings.EqualFold(args[0], "SENTINEL") {
+			return "-BUSY sentinel busy\r\n"
+		}
+		return basicHandler(args)
+	})
+	c := NewClient([]string{down.addr(), busy.addr()})
+	defer c.Close()
+
+	_, err := c.do("SENTINEL", "sentinels", "mymaster")
+	var nerr *NoSentinelReachableError
+	if !errors.As(err, &nerr) {
+		t.Fatalf("do() error = %#v, want *NoSentinelReachableError", err)
+	}
+	attempts := nerr.Attempts()
+	if len(attempts) != 2 || attempts[0].Sentinel != down.addr() || attempts[1].Sentinel != busy.addr() {
+		t.Fatalf("Attempts() = %v", attempts)
+	}
+	var rerr redis.Error
+	if !errors.As(err, &rerr) || !strings.HasPrefix(string(rerr), "BUSY") {
+		t.Fatalf("error does not unwrap to the last error reply: %v", err)
+	}
+	for _, addr := range []string{down.addr(), busy.addr()} {
+		if !strings.Contains(err.Error(), addr) {
+			t.Errorf("error %q does not mention sentinel %s", err, addr)
+		}
+	}
+}
+
+func TestReplicasUnknownMasterAllSentinels(t *testing.T) {
+	handler := func(args []string) string {
+		if strings.EqualFold(args[0], "SENTINEL") {
+			return "-ERR No such master with that name\r\n"
+		}
+		return basicHandler(args)
+	}
+	a, b := newFake(t, handler), newFake(t, handler)
+	c := NewClient([]string{a.addr(), b.addr()})
+	defer c.Close()
+
+	if _, err := c.Replicas("mymaster"); !errors.Is(err, ErrMasterUnknown) {
+		t.Fatalf("Replicas() error = %v, want ErrMasterUnknown", err)
+	}
+	if n := a.calls("SENTINEL slaves mymaster") + b.calls("SENTINEL slaves mymaster"); n != 0 {
+		t.Errorf("unknown master retried with slaves %d times", n)
+	}
+}
+
+func TestIsAuthError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{redis.Error("NOAUTH Authentication required."), true},
+		{redis.Error("WRONGPASS invalid username-password pair or user is disabled."), true},
+		{redis.Error("ERR invalid password"), true},
+		{redis.Error("ERR AUTH <password> called without any password configured for the default user."), true},
+		{redis.Error("NOPERM this user has no permissions to run the 'sentinel' command"), false},
+		{redis.Error("ERR unknown command"), false},
+		{errors.New("NOAUTH"), false},
+	}
+	for _, tt := range tests {
+		if got := isAuthError(tt.err); got != tt.want {
+			t.Errorf("isAuthError(%q) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestNoPermIsNotAuthError(t *testing.T) {
+	denied := newFake(t, func(args []string) string {
+		if strings.EqualFold(args[0], "SENTINEL") {
+			return "-NOPERM this user has no permissions to run the 'sentinel' command\r\n"
+		}
+		return basicHandler(args)
+	})
+	allowed := newFake(t, basicHandler)
+	c := NewClient([]string{denied.addr(), allowed.addr()})
+	defer c.Close()
+
+	if _, err := c.MasterAddress("mymaster"); err != nil {
+		var aerr *AuthError
+		if errors.As(err, &aerr) {
+			t.Fatalf("NOPERM reported as authentication failure: %v", err)
+		}
+		t.Fatal(err)
+	}
+}
+
+// silentFake starts a fake server answering every command too late.
+func silentFake(t *testing.T) *fakeSentinel {
+	return newFake(t, func(args []string) string {
+		time.Sleep(200 * time.Millisecond)
+		return "+OK\r\n"
+	})
+}
+
+func TestPoolDialTimeout(t *testing.T) {
+	s := newFake(t, pointTo(silentFake(t).addr()))
+	conf := testConfig(s.addr())
+	conf.RedisTimeouts.Read = 20 * time.Millisecond
+	p, err := NewPool(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	_, err = p.DialContext(context.Background())
+	if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+		t.Fatalf("DialContext() error = %#v, want net.Error with Timeout()", err)
+	}
+}
+
+func TestSentinelTimeoutsAggregate(t *testing.T) {
+	a, b := silentFake(t), silentFake(t)
+	conf := testConfig(a.addr(), b.addr())
+	conf.SentinelTimeouts.Read = 20 * time.Millisecond
+	p, err := NewPool(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	_, err = p.DialContext(context.Background())
+	if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+		t.Fatalf("DialContext() error = %#v, want net.Error with Timeout()", err)
+	}
+	if !errors.Is(err, ErrNoSentinelReachable) {
+		t.Errorf("DialContext() error = %v, want ErrNoSentinelReachable", err)
+	}
+}
+
+func TestSentinelFailuresNotTimeout(t *testing.T) {
+	slow := silentFake(t)
+	c := NewClientWithOptions([]string{slow.addr(), closedAddr(t)},
+		WithDialOptions(redis.DialReadTimeout(20*time.Millisecond)))
+	defer c.Close()
+
+	_, err := c.MasterAddress("mymaster")
+	if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+		t.Errorf("MasterAddress() error %v reports Timeout() with refused connection", err)
+	}
+}
+
+func TestTotalTimeout(t *testing.T) {
+	a, b, c := silentFake(t), silentFake(t), silentFake(t)
+	client := NewClientWithOptions([]string{a.addr(), b.addr(), c.addr()},
+		WithDialOptions(redis.DialReadTimeout(time.Second)), WithTotalTimeout(50*time.Millisecond))
+	defer client.Close()
+
+	start := time.Now()
+	_, err := client.MasterAddress("mymaster")
+	elapsed := time.Since(start)
+	var terr *TimeoutError
+	if !errors.As(err, &terr) {
+		t.Fatalf("MasterAddress() error = %v, want *TimeoutError", err)
+	}
+	if want := (TimeoutError{Attempts: 1, Sentinels: 3, Total: 50 * time.Millisecond}); *terr != want {
+		t.Errorf("MasterAddress() error = %+v, want %+v", *terr, want)
+	}
+	if elapsed > 150*time.Millisecond {
+		t.Errorf("MasterAddress() took %v, want it cut at the total timeout", elapsed)
+	}
+	if n := b.calls(getMaster) + c.calls(getMaster); n != 0 {
+		t.Errorf("%d sentinels queried after the total timeout", n)
+	}
+}
+
+func TestMasterDownCheck(t *testing.T) {
+	var mu sync.Mutex
+	flags := "master"
+	s := newFake(t, func(args []string) string {
+		if len(args) == 3 && strings.EqualFold(args[1], "master") {
+			if args[2] != "mymaster" {
+				return "-ERR No such master with that name\r\n"
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			return bulkArr("name", "mymaster", "ip", "10.0.0.1", "port", "6379", "flags", flags)
+		}
+		return basicHandler(args)
+	})
+	c := NewClientWithOptions([]string{s.addr()}, WithMasterDownCheck())
+	defer c.Close()
+
+	addr, err := c.MasterAddress("mymaster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr != "10.0.0.1:6379" {
+		t.Errorf("MasterAddress() = %q, want 10.0.0.1:6379", addr)
+	}
+	if n := s.calls(getMaster); n != 0 {
+		t.Errorf("get-master-addr-by-name called %d times, want SENTINEL master", n)
+	}
+
+	for _, f := range []string{"s_down,master", "o_down,s_down,master", "master,failover_in_progress"} {
+		mu.Lock()
+		flags = f
+		mu.Unlock()
+		_, err := c.MasterAddress("mymaster")
+		var derr *MasterDownError
+		if !errors.As(err, &derr) || !errors.Is(err, ErrMasterDown) {
+			t.Fatalf("%s: MasterAddress() error = %v, want *MasterDownError", f, err)
+		}
+		want := MasterDownError{Name: "mymaster", Addr: Addr{Host: "10.0.0.1", Port: 6379}, Flags: strings.Split(f, ","), Sentinel: s.addr()}
+		if !reflect.DeepEqual(*derr, want) {
+			t.Errorf("%s: error = %+v, want %+v", f, *derr, want)
+		}
+	}
+
+	if _, err := c.MasterAddress("other"); !errors.Is(err, ErrMasterUnknown) {
+		t.Errorf("MasterAddress(other) error = %v, want ErrMasterUnknown", err)
+	}
+}
@@ -0,0 +1,273 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Names of the channels sentinel publishes events to.
+const (
+	ChannelSwitchMaster             = "+switch-master"
+	ChannelSDown                    = "+sdown"
+	ChannelSDownCleared             = "-sdown"
+	ChannelODown                    = "+odown"
+	ChannelODownCleared             = "-odown"
+	ChannelSlave                    = "+slave"
+	ChannelSentinel                 = "+sentinel"
+	ChannelResetMaster              = "+reset-master"
+	ChannelReboot                   = "+reboot"
+	ChannelConvertToSlave           = "+convert-to-slave"
+	ChannelDupSentinel              = "-dup-sentinel"
+	ChannelTryFailover              = "+try-failover"
+	ChannelElectedLeader            = "+elected-leader"
+	ChannelFailoverDetected         = "+failover-detected"
+	ChannelSelectedSlave            = "+selected-slave"
+	ChannelPromotedSlave            = "+promoted-slave"
+	ChannelSlaveReconfSent          = "+slave-reconf-sent"
+	ChannelSlaveReconfInprog        = "+slave-reconf-inprog"
+	ChannelSlaveReconfDone          = "+slave-reconf-done"
+	ChannelFailoverEnd              = "+failover-end"
+	ChannelFailoverEndForTimeout    = "+failover-end-for-timeout"
+	ChannelFailoverAbortNoGoodSlave = "-failover-abort-no-good-slave"
+	ChannelConfigUpdateFrom         = "+config-update-from"
+	ChannelTilt                     = "+tilt"
+	ChannelTiltCleared              = "-tilt"
+	ChannelNewEpoch                 = "+new-epoch"
+
+	// ChannelFailoverStatePrefix is a common prefix of the channels
+	// announcing failover state changes, e.g. +failover-state-select-slave.
+	ChannelFailoverStatePrefix = "+failover-state-"
+)
+
+// instanceChannels is a set of the channels with payloads in the instance
+// details format.
+var instanceChannels = map[string]bool{
+	ChannelSDown:                    true,
+	ChannelSDownCleared:             true,
+	ChannelODown:                    true,
+	ChannelODownCleared:             true,
+	ChannelSlave:                    true,
+	ChannelSentinel:                 true,
+	ChannelResetMaster:              true,
+	ChannelReboot:                   true,
+	ChannelConvertToSlave:           true,
+	ChannelDupSentinel:              true,
+	ChannelTryFailover:              true,
+	ChannelElectedLeader:            true,
+	ChannelFailoverDetected:         true,
+	ChannelSelectedSlave:            true,
+	ChannelPromotedSlave:            true,
+	ChannelSlaveReconfSent:          true,
+	ChannelSlaveReconfInprog:        true,
+	ChannelSlaveReconfDone:          true,
+	ChannelFailoverEnd:              true,
+	ChannelFailoverEndForTimeout:    true,
+	ChannelFailoverAbortNoGoodSlave: true,
+	ChannelConfigUpdateFrom:         true,
+}
+
+// Event is a sentinel event received over the sentinel pub/sub interface.
+// Concrete types are InstanceEvent, FailoverStateEvent, SwitchMasterEvent and
+// RawEvent for events that could not be parsed into any of the other types.
+type Event interface {
+	// EventType returns the name of the channel event was published to,
+	// e.g. "+sdown".
+	EventType() string
+}
+
+// Instance identifies redis or sentinel instance in the event payloads.
+type Instance struct {
+	// Type is one of "master", "slave" or "sentinel".
+	Type string
+	Name string
+	Addr Addr
+}
+
+// InstanceEvent is an event about a single instance, e.g. +sdown, -odown or
+// +slave. The payload format is
+// "<instance-type> <name> <ip> <port> @ <master-name> <master-ip> <master-port>".
+type InstanceEvent struct {
+	Type     string
+	Instance Instance
+	// Master is the master of the slave or sentinel instance. It is not set
+	// for events about master instances.
+	Master Instance
+	// Extra holds any additional details following the instance
+	// description, e.g. "#quorum 2/2" of the +odown event.
+	Extra string
+}
+
+// EventType returns the name of the event channel.
+func (e InstanceEvent) EventType() string {
+	return e.Type
+}
+
+// FailoverStateEvent is published when failover enters a new state, e.g.
+// +failover-state-select-slave.
+type FailoverStateEvent struct {
+	InstanceEvent
+	// State is the new failover state, e.g. "select-slave".
+	State string
+}
+
+// SwitchMasterEvent is published when the master address changes.
+type SwitchMasterEvent struct {
+	MasterSwitch
+}
+
+// EventType returns "+switch-master".
+func (e SwitchMasterEvent) EventType() string {
+	return "+switch-master"
+}
+
+// RawEvent is an event with payload not known to the parser.
+type RawEvent struct {
+	Channel string
+	Payload string
+}
+
+// EventType returns the name of the event channel.
+func (e RawEvent) EventType() string {
+	return e.Channel
+}
+
+// ReconnectedEvent is a synthetic event delivered by Events after a dropped
+// subscription was resumed on another sentinel. Events published while
+// disconnected are lost, so consumers should resynchronize their state.
+type ReconnectedEvent struct {
+	// Addr is the address of the sentinel subscription was resumed on.
+	Addr string
+}
+
+// EventType returns "reconnected". It is not a name of any sentinel channel.
+func (e ReconnectedEvent) EventType() string {
+	return "reconnected"
+}
+
+// Events subscribes to all the events published by sentinel. Events are
+// delivered over the returned channel, which is closed once ctx is done. Error
+// is returned if none of the sentinels can be subscribed to initially.
+//
+// If the subscribed sentinel connection drops the subscription is resumed on
+// the next sentinel, backing off between the attempts, and ReconnectedEvent
+// is delivered.
+//
+// The channel is buffered like the delivery channels of WatchMasters, so a
+// slow consumer does not hold up the subscription. Once the buffer is full,
+// the oldest event is dropped in favour of the new one.
+func (sc *Client) Events(ctx context.Context) (<-chan Event, error) {
+	ch := make(chan Event, watchBufferSize)
+	// Events are delivered by the subscription goroutine only, which is
+	// also the one closing the channel.
+	deliver := func(ev Event) {
+		for {
+			select {
+			case ch <- ev:
+				return
+			default:
+			}
+			select {
+			case <-ch:
+			default:
+			}
+		}
+	}
+
+	s := sc.newSubscription([]string{"*"})
+	s.handle = func(channel, payload string) {
+		ev, err := ParseEvent(channel, payload)
+		if err != nil {
+			ev = RawEvent{Channel: channel, Payload: payload}
+		}
+		deliver(ev)
+	}
+	s.reconnected = func(addr string) {
+		deliver(ReconnectedEvent{Addr: addr})
+	}
+
+	psc, err := s.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		defer close(ch)
+		s.run(ctx, psc)
+	}()
+	return ch, nil
+}
+
+// ParseEvent parses an event published to the sentinel channel. Events
+// published to the channels not known to the parser, or not carrying instance
+// details, are returned as RawEvent. Error is returned if the payload of a
+// known event is malformed.
+func ParseEvent(channel, payload string) (Event, error) {
+	switch {
+	case channel == ChannelSwitchMaster:
+		ev, err := parseMasterSwitch(payload)
+		if err != nil {
+			return nil, err
+		}
+		return SwitchMasterEvent{ev}, nil
+	case strings.HasPrefix(channel, ChannelFailoverStatePrefix):
+		ev, err := parseInstanceEvent(channel, payload)
+		if err != nil {
+			return nil, err
+		}
+		return FailoverStateEvent{
+			InstanceEvent: ev,
+			State:         strings.TrimPrefix(channel, ChannelFailoverStatePrefix),
+		}, nil
+	case instanceChannels[channel]:
+		ev, err := parseInstanceEvent(channel, payload)
+		if err != nil {
+			return nil, err
+		}
+		return ev, nil
+	}
+
+	if ev, err := parseInstanceEvent(channel, payload); err == nil {
+		return ev, nil
+	}
+	return RawEvent{Channel: channel, Payload: payload}, nil
+}
+
+// parseInstanceEvent parses payloads in the instance details format.
+func parseInstanceEvent(channel, payload string) (InstanceEvent, error) {
+	parts := strings.Fields(payload)
+	inst, err := parseInstance(parts)
+	if err != nil {
+		return InstanceEvent{}, fmt.Errorf("sentinel: invalid %s payload %q: %w", channel, payload, err)
+	}
+	ev := InstanceEvent{Type: channel, Instance: inst}
+	parts = parts[4:]
+
+	if len(parts) >= 4 && parts[0] == "@" {
+		addr, err := parseAddr(parts[2:4])
+		if err != nil {
+			return InstanceEvent{}, fmt.Errorf("sentinel: invalid %s payload %q: %w", channel, payload, err)
+		}
+		ev.Master = Instance{Type: "master", Name: parts[1], Addr: addr}
+		parts = parts[4:]
+	}
+	ev.Extra = strings.Join(parts, " ")
+	return ev, nil
+}
+
+// parseInstance parses "<instance-type> <name> <ip> <port>" prefix of parts.
+func parseInstance(parts []string) (Instance, error) {
+	if len(parts) < 4 {
+		return Instance{}, errors.New("missing instance details")
+	}
+	switch parts[0] {
+	case "master", "slave", "sentinel":
+	default:
+		return Instance{}, fmt.Errorf("unknown instance type %q", parts[0])
+	}
+	addr, err := parseAddr(parts[2:4])
+	if err != nil {
+		return Instance{}, err
+	}
+	return Instance{Type: parts[0], Name: parts[1], Addr: addr}, nil
+}
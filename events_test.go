@@ -0,0 +1,132 @@
+package sentinel
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseEvent(t *testing.T) {
+	master := Instance{Type: "master", Name: "mymaster", Addr: Addr{Host: "10.0.0.1", Port: 6379}}
+	tests := []struct {
+		channel, payload string
+		want             Event
+	}{
+		{ChannelSDown, "master mymaster 10.0.0.1 6379",
+			InstanceEvent{Type: ChannelSDown, Instance: master}},
+		{ChannelODown, "master mymaster 10.0.0.1 6379 #quorum 2/2",
+			InstanceEvent{Type: ChannelODown, Instance: master, Extra: "#quorum 2/2"}},
+		{ChannelSlave, "slave 10.0.0.2:6380 10.0.0.2 6380 @ mymaster 10.0.0.1 6379",
+			InstanceEvent{
+				Type:     ChannelSlave,
+				Instance: Instance{Type: "slave", Name: "10.0.0.2:6380", Addr: Addr{Host: "10.0.0.2", Port: 6380}},
+				Master:   master,
+			}},
+		{ChannelSDown, "slave [fd00::2]:6380 fd00::2 6380 @ mymaster 10.0.0.1 6379",
+			InstanceEvent{
+				Type:     ChannelSDown,
+				Instance: Instance{Type: "slave", Name: "[fd00::2]:6380", Addr: Addr{Host: "fd00::2", Port: 6380}},
+				Master:   master,
+			}},
+		{ChannelSentinel, "sentinel 8f2ad7bcdd11bd1236e245f34e0a2c0b0ce8dfb3 10.0.0.5 26379 @ mymaster 10.0.0.1 6379",
+			InstanceEvent{
+				Type:     ChannelSentinel,
+				Instance: Instance{Type: "sentinel", Name: "8f2ad7bcdd11bd1236e245f34e0a2c0b0ce8dfb3", Addr: Addr{Host: "10.0.0.5", Port: 26379}},
+				Master:   master,
+			}},
+		{ChannelSlaveReconfSent, "slave 10.0.0.3:6381 10.0.0.3 6381 @ mymaster 10.0.0.1 6379",
+			InstanceEvent{
+				Type:     ChannelSlaveReconfSent,
+				Instance: Instance{Type: "slave", Name: "10.0.0.3:6381", Addr: Addr{Host: "10.0.0.3", Port: 6381}},
+				Master:   master,
+			}},
+		{ChannelSwitchMaster, "mymaster 10.0.0.1 6379 10.0.0.2 6380",
+			SwitchMasterEvent{MasterSwitch{Name: "mymaster", Old: master.Addr, New: Addr{Host: "10.0.0.2", Port: 6380}}}},
+		{"+failover-state-select-slave", "master mymaster 10.0.0.1 6379",
+			FailoverStateEvent{InstanceEvent: InstanceEvent{Type: "+failover-state-select-slave", Instance: master}, State: "select-slave"}},
+		{ChannelNewEpoch, "5", RawEvent{Channel: ChannelNewEpoch, Payload: "5"}},
+		{ChannelTilt, "#tilt mode entered", RawEvent{Channel: ChannelTilt, Payload: "#tilt mode entered"}},
+		{"+monitor", "master mymaster 10.0.0.1 6379 quorum 2",
+			InstanceEvent{Type: "+monitor", Instance: master, Extra: "quorum 2"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseEvent(tt.channel, tt.payload)
+		if err != nil {
+			t.Errorf("ParseEvent(%q, %q): %v", tt.channel, tt.payload, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseEvent(%q, %q) = %#v, want %#v", tt.channel, tt.payload, got, tt.want)
+		}
+		if got.EventType() != tt.channel {
+			t.Errorf("EventType() = %q, want %q", got.EventType(), tt.channel)
+		}
+	}
+}
+
+func TestParseEventMalformed(t *testing.T) {
+	tests := []struct{ channel, payload string }{
+		{ChannelSDown, "master mymaster 10.0.0.1"},
+		{ChannelSDown, "replica mymaster 10.0.0.1 6379"},
+		{ChannelSlave, "slave 10.0.0.2:6380 10.0.0.2 6380 @ mymaster 10.0.0.1 port"},
+		{ChannelSwitchMaster, "mymaster 10.0.0.1 6379"},
+		{ChannelSwitchMaster, "mymaster 10.0.0.1 6379 10.0.0.2 port"},
+		{"+failover-state-reconf-slaves", "master mymaster"},
+	}
+	for _, tt := range tests {
+		if ev, err := ParseEvent(tt.channel, tt.payload); err == nil {
+			t.Errorf("ParseEvent(%q, %q) = %#v, want error", tt.channel, tt.payload, ev)
+		}
+	}
+}
+
+func TestEventsSlowConsumer(t *testing.T) {
+	const switches = 3 * watchBufferSize
+	var subscriptions int32
+	s := newFake(t, func(args []string) string {
+		if !strings.EqualFold(args[0], "PSUBSCRIBE") {
+			return basicHandler(args)
+		}
+		reply := "*3\r\n" + bulk("psubscribe") + bulk("*") + ":1\r\n"
+		if atomic.AddInt32(&subscriptions, 1) > 1 {
+			return reply
+		}
+		for i := 0; i < switches; i++ {
+			reply += switchMessage("mymaster", 7000+i, 7001+i)
+		}
+		return reply + closeAfter
+	})
+	c := NewClient([]string{s.addr()})
+	defer c.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := c.Events(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Subscription is resumed once all the events of the dropped connection
+	// are handled, ReconnectedEvent is the last one delivered before the
+	// channel is closed.
+	waitFor(t, "resubscription", func() bool { return atomic.LoadInt32(&subscriptions) > 1 })
+	cancel()
+
+	var got []Event
+	for ev := range events {
+		got = append(got, ev)
+	}
+	if len(got) != watchBufferSize {
+		t.Fatalf("%d events buffered, want %d", len(got), watchBufferSize)
+	}
+	if _, ok := got[len(got)-1].(ReconnectedEvent); !ok {
+		t.Errorf("last event %v, want ReconnectedEvent", got[len(got)-1])
+	}
+	for i, ev := range got[:len(got)-1] {
+		want := 7001 + switches - watchBufferSize + 1 + i
+		if sw, ok := ev.(SwitchMasterEvent); !ok || sw.New.Port != want {
+			t.Errorf("event %d = %v, want switch to port %d", i, ev, want)
+		}
+	}
+}
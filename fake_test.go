@@ -0,0 +1,221 @@
+package sentinel
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSentinel is a minimal RESP server used in place of sentinel and redis
+// servers in tests. Every command is answered by the handler with a raw RESP
+// reply. Reply ending with closeAfter is written and the connection closed.
+type fakeSentinel struct {
+	ln      net.Listener
+	handler func(args []string) string
+	idle    time.Duration
+
+	mu    sync.Mutex
+	count map[string]int
+	conns int
+	open  int
+}
+
+// closeAfter makes the fake server close the connection after writing reply.
+const closeAfter = "CLOSEAFTER"
+
+func newFake(t *testing.T, h func(args []string) string) *fakeSentinel {
+	return newFakeIdle(t, h, 0)
+}
+
+// newFakeIdle starts a fake server closing connections idle for longer than
+// idle, as sentinel does with its timeout setting.
+func newFakeIdle(t *testing.T, h func(args []string) string, idle time.Duration) *fakeSentinel {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return serveFake(t, ln, h, idle)
+}
+
+// serveFake serves commands received over ln with the handler.
+func serveFake(t *testing.T, ln net.Listener, h func(args []string) string, idle time.Duration) *fakeSentinel {
+	f := &fakeSentinel{ln: ln, handler: h, count: map[string]int{}, idle: idle}
+	go f.accept()
+	t.Cleanup(func() { ln.Close() })
+	return f
+}
+
+func (f *fakeSentinel) accept() {
+	for {
+		c, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.conns++
+		f.mu.Unlock()
+		go f.serve(c)
+	}
+}
+
+func (f *fakeSentinel) addr() string {
+	return f.ln.Addr().String()
+}
+
+// calls returns the number of times the command was received.
+func (f *fakeSentinel) calls(cmd string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.count[strings.ToUpper(cmd)]
+}
+
+// openConns returns the number of currently open connections.
+func (f *fakeSentinel) openConns() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.open
+}
+
+// dialed returns the number of accepted connections.
+func (f *fakeSentinel) dialed() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.conns
+}
+
+func (f *fakeSentinel) serve(c net.Conn) {
+	f.mu.Lock()
+	f.open++
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.open--
+		f.mu.Unlock()
+	}()
+	defer c.Close()
+
+	r := bufio.NewReader(c)
+	for {
+		if f.idle > 0 {
+			c.SetReadDeadline(time.Now().Add(f.idle))
+		}
+		line, err := r.ReadString('\n')
+		if err != nil || len(line) < 2 {
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		args := make([]string, n)
+		for i := range args {
+			if _, err := r.ReadString('\n'); err != nil {
+				return
+			}
+			a, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			args[i] = strings.TrimSuffix(a, "\r\n")
+		}
+		f.mu.Lock()
+		f.count[strings.ToUpper(strings.Join(args, " "))]++
+		f.mu.Unlock()
+
+		resp := f.handler(args)
+		if strings.HasSuffix(resp, closeAfter) {
+			c.Write([]byte(strings.TrimSuffix(resp, closeAfter)))
+			return
+		}
+		c.Write([]byte(resp))
+	}
+}
+
+// bulkArr formats items as RESP array of bulk strings.
+func bulkArr(items ...string) string {
+	s := fmt.Sprintf("*%d\r\n", len(items))
+	for _, it := range items {
+		s += bulk(it)
+	}
+	return s
+}
+
+// bulk formats s as RESP bulk string.
+func bulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+// basicHandler answers as sentinel monitoring master "mymaster" at
+// [fd00::1]:6379 with a single healthy replica.
+func basicHandler(args []string) string {
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "CLIENT", "AUTH":
+		return "+OK\r\n"
+	case "INFO":
+		return bulk("# Sentinel\r\nsentinel_tilt:0\r\n")
+	case "SENTINEL":
+		if len(args) < 2 {
+			break
+		}
+		switch strings.ToLower(args[1]) {
+		case "get-master-addr-by-name":
+			if args[2] == "mymaster" {
+				return bulkArr("fd00::1", "6379")
+			}
+			return "*-1\r\n"
+		case "replicas":
+			return "*1\r\n" + bulkArr("ip", "10.0.0.2", "port", "6380", "flags", "slave", "master-link-status", "ok")
+		}
+	}
+	return "-ERR unknown\r\n"
+}
+
+// masterRole is the ROLE reply of master without replicas.
+const masterRole = "*3\r\n$6\r\nmaster\r\n:0\r\n*0\r\n"
+
+// pointTo returns sentinel handler reporting master at addr.
+func pointTo(addr string) func(args []string) string {
+	host, port, _ := net.SplitHostPort(addr)
+	return func(args []string) string {
+		if strings.EqualFold(args[0], "SENTINEL") && len(args) > 1 && strings.EqualFold(args[1], "get-master-addr-by-name") {
+			return bulkArr(host, port)
+		}
+		return basicHandler(args)
+	}
+}
+
+// newFakeMaster starts a fake redis master and a fake sentinel pointing to
+// it, recording commands received by the master.
+func newFakeMaster(t *testing.T) (sentinel *fakeSentinel, cmds func() []string) {
+	var mu sync.Mutex
+	var got []string
+	master := newFake(t, func(args []string) string {
+		mu.Lock()
+		got = append(got, strings.Join(args, " "))
+		mu.Unlock()
+		switch strings.ToUpper(args[0]) {
+		case "ROLE":
+			return masterRole
+		case "PING":
+			return "+PONG\r\n"
+		}
+		return "+OK\r\n"
+	})
+	return newFake(t, pointTo(master.addr())), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), got...)
+	}
+}
+
+// testConfig returns valid Config with one second timeouts.
+func testConfig(sentinels ...string) Config {
+	conf := Config{Master: "mymaster", Sentinels: sentinels}
+	conf.SentinelTimeouts.Connect, conf.SentinelTimeouts.Read, conf.SentinelTimeouts.Write = time.Second, time.Second, time.Second
+	conf.RedisTimeouts.Connect, conf.RedisTimeouts.Read, conf.RedisTimeouts.Write = time.Second, time.Second, time.Second
+	return conf
+}
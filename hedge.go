@@ -0,0 +1,97 @@
+package sentinel
+
+import (
+	"context"
+	"time"
+)
+
+// hedgedResult is a result of a single query issued by doHedged.
+type hedgedResult struct {
+	addr  string
+	reply interface{}
+	tilt  bool
+	err   error
+}
+
+// doHedged executes single redis command on up to sc.hedge sentinels at once
+// and returns the first successful reply, see WithHedgedRequests. All the
+// sentinels are tried before giving up. The sentinel replying first becomes
+// active, its address is returned along with the reply.
+func (sc *Client) doHedged(parent context.Context, cmd string, args ...interface{}) (interface{}, string, error) {
+	addrs := sc.sentinelsFromActive()
+	if len(addrs) == 0 {
+		return nil, "", ErrNoSentinelsConfigured
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	if sc.totalTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, sc.totalTimeout)
+		defer cancel()
+	}
+
+	// Results channel is buffered, so abandoned queries never block and
+	// return their connections once cancelled.
+	results := make(chan hedgedResult, len(addrs))
+	launched, pending := 0, 0
+	launch := func() {
+		addr := addrs[launched]
+		launched++
+		pending++
+		go func() {
+			reply, tilt, err := sc.doOnce(ctx, addr, true, cmd, args...)
+			results <- hedgedResult{addr: addr, reply: reply, tilt: tilt, err: err}
+		}()
+	}
+
+	timer := time.NewTimer(sc.hedgeDelay)
+	defer timer.Stop()
+
+	var failed []AttemptError
+	var tilted string
+	launch()
+	for pending > 0 {
+		select {
+		case r := <-results:
+			pending--
+			switch {
+			case r.err == nil && !r.tilt:
+				sc.setActive(r.addr)
+				sc.succeeded(r.addr)
+				return r.reply, r.addr, nil
+			case r.err == nil:
+				if tilted == "" {
+					tilted = r.addr
+				}
+				failed = append(failed, AttemptError{Sentinel: r.addr, Err: errTilt})
+			case isAuthError(r.err):
+				return nil, r.addr, &AuthError{Addr: r.addr, Err: r.err}
+			default:
+				failed = append(failed, AttemptError{Sentinel: r.addr, Err: r.err})
+			}
+			if launched < len(addrs) && !ctxDone(ctx) {
+				launch()
+			}
+		case <-timer.C:
+			if launched < len(addrs) && pending < sc.hedge {
+				launch()
+			}
+			timer.Reset(sc.hedgeDelay)
+		case <-ctx.Done():
+			return nil, "", sc.contextError(parent, launched)
+		}
+	}
+
+	if tilted != "" {
+		// Unreliable answer is better than no answer at all.
+		reply, _, err := sc.doOnce(ctx, tilted, false, cmd, args...)
+		if err == nil {
+			return reply, tilted, nil
+		}
+		failed = append(failed, AttemptError{Sentinel: tilted, Err: err})
+	}
+	if ctxDone(ctx) {
+		return nil, "", sc.contextError(parent, launched)
+	}
+	return nil, "", unreachable(failed)
+}
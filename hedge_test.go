@@ -0,0 +1,72 @@
+package sentinel
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHedgedFirstReplyWins(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	release := make(chan struct{})
+	slow := newFake(t, func(args []string) string {
+		if strings.EqualFold(args[0], "SENTINEL") {
+			select {
+			case <-release:
+			case <-time.After(5 * time.Second):
+			}
+		}
+		return basicHandler(args)
+	})
+	fast := newFake(t, basicHandler)
+	c := NewClientWithOptions([]string{slow.addr(), fast.addr()}, WithHedgedRequests(2, delay))
+	defer c.Close()
+
+	// Keep an idle connection to the fast sentinel, so the baseline already
+	// accounts for the connection the winning query returns to the pool.
+	conn, _, err := c.getConn(context.Background(), fast.addr(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.putConn(conn, nil)
+	idle := func() (slowIdle, fastIdle int) {
+		c.Lock()
+		defer c.Unlock()
+		return len(c.idle[slow.addr()]), len(c.idle[fast.addr()])
+	}
+	goroutines := runtime.NumGoroutine()
+
+	start := time.Now()
+	addr, err := c.MasterAddress("mymaster")
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr != "[fd00::1]:6379" {
+		t.Errorf("MasterAddress() = %q, want [fd00::1]:6379", addr)
+	}
+	if elapsed > 4*delay {
+		t.Errorf("MasterAddress() took %v, want first reply after %v delay", elapsed, delay)
+	}
+	if n := slow.calls(getMaster); n != 1 {
+		t.Errorf("slow sentinel queried %d times, want 1", n)
+	}
+	if got := c.sentinelsFromActive()[0]; got != fast.addr() {
+		t.Errorf("active sentinel = %s, want %s", got, fast.addr())
+	}
+
+	close(release)
+	waitFor(t, "cancelled query to close its connection", func() bool { return slow.openConns() == 0 })
+	waitFor(t, "goroutines to return to baseline", func() bool { return runtime.NumGoroutine() <= goroutines })
+	if slowIdle, fastIdle := idle(); slowIdle != 0 || fastIdle != 1 {
+		t.Errorf("idle connections slow=%d fast=%d, want 0 and 1", slowIdle, fastIdle)
+	}
+	if n := len(c.slots); n != 0 {
+		t.Errorf("%d connection slots held after lookup", n)
+	}
+	if n := fast.dialed(); n != 1 {
+		t.Errorf("fast sentinel dialed %d times, want 1", n)
+	}
+}
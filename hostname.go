@@ -0,0 +1,73 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// HostnameMode selects how master hostnames announced by sentinels with
+// announce-hostnames enabled are handled.
+type HostnameMode string
+
+// Hostname modes. HostnameKeep is used by default.
+const (
+	// HostnameKeep passes hostnames through to be resolved when dialing,
+	// e.g. to keep the name for TLS server verification.
+	HostnameKeep HostnameMode = "keep"
+	// HostnameResolve resolves hostnames to IP addresses before they are
+	// returned.
+	HostnameResolve HostnameMode = "resolve"
+	// HostnameReject fails lookups answered with a hostname instead of an
+	// IP address.
+	HostnameReject HostnameMode = "reject"
+)
+
+// ErrHostnameNotAllowed is returned wrapped in *HostnameError if sentinel
+// announces hostname while HostnameReject mode is used.
+var ErrHostnameNotAllowed = errors.New("hostname not allowed")
+
+// HostnameError is returned when master hostname announced by sentinel could
+// not be resolved or is not allowed, see WithHostnames. Other sentinels are
+// tried before HostnameError is returned.
+type HostnameError struct {
+	Host     string
+	Sentinel string
+	Err      error
+}
+
+func (e *HostnameError) Error() string {
+	if e.Sentinel == "" {
+		return fmt.Sprintf("sentinel: master hostname %q: %s", e.Host, e.Err)
+	}
+	return fmt.Sprintf("sentinel: master hostname %q reported by %s: %s", e.Host, e.Sentinel, e.Err)
+}
+
+// Unwrap returns the resolution error.
+func (e *HostnameError) Unwrap() error {
+	return e.Err
+}
+
+// resolveHost applies the hostname mode to the master address reported by
+// the sentinel.
+func (sc *Client) resolveHost(ctx context.Context, sentinel string, addr Addr) (Addr, error) {
+	if sc.hostnames == "" || sc.hostnames == HostnameKeep || net.ParseIP(addr.Host) != nil {
+		return addr, nil
+	}
+	if sc.hostnames == HostnameReject {
+		return Addr{}, &HostnameError{Host: addr.Host, Sentinel: sentinel, Err: ErrHostnameNotAllowed}
+	}
+	resolver := sc.resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	ips, err := resolver.LookupIPAddr(ctx, addr.Host)
+	if err == nil && len(ips) == 0 {
+		err = errors.New("no addresses found")
+	}
+	if err != nil {
+		return Addr{}, &HostnameError{Host: addr.Host, Sentinel: sentinel, Err: err}
+	}
+	return Addr{Host: ips[0].IP.String(), Port: addr.Port}, nil
+}
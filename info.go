@@ -0,0 +1,490 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// ErrMasterUnknown is returned when sentinel does not monitor a master with
+// the requested name.
+var ErrMasterUnknown = errors.New("sentinel: no such master")
+
+// ErrUnsupportedCommand is returned when sentinel server is too old to support
+// the requested command.
+var ErrUnsupportedCommand = errors.New("sentinel: command not supported by server")
+
+// MasterInfo is a state of the monitored master as reported by sentinel.
+type MasterInfo struct {
+	Name              string
+	IP                string
+	Port              int
+	Flags             []string
+	NumSlaves         int
+	NumOtherSentinels int
+	Quorum            int
+	LastOKPingReply   time.Duration
+	ConfigEpoch       int64
+	DownAfter         time.Duration
+	FailoverTimeout   time.Duration
+	FailoverState     string
+}
+
+// Addr returns master address.
+func (m MasterInfo) Addr() Addr {
+	return Addr{Host: m.IP, Port: m.Port}
+}
+
+// Masters returns the state of all masters monitored by sentinel.
+func (sc *Client) Masters() ([]MasterInfo, error) {
+	res, err := redis.Values(sc.do("SENTINEL", "masters"))
+	if err != nil {
+		return nil, err
+	}
+
+	masters := make([]MasterInfo, 0, len(res))
+	for _, r := range res {
+		m, err := redis.StringMap(r, nil)
+		if err != nil {
+			return nil, err
+		}
+		info, err := parseMasterInfo(m)
+		if err != nil {
+			return nil, err
+		}
+		masters = append(masters, info)
+	}
+	return masters, nil
+}
+
+// Master returns the state of a single named master monitored by sentinel.
+// ErrMasterUnknown is returned if sentinel does not monitor such master.
+func (sc *Client) Master(name string) (MasterInfo, error) {
+	m, err := redis.StringMap(sc.do("SENTINEL", "master", name))
+	if isNoSuchMaster(err) {
+		return MasterInfo{}, ErrMasterUnknown
+	}
+	if err != nil {
+		return MasterInfo{}, err
+	}
+	return parseMasterInfo(m)
+}
+
+// isErrorReply checks if err is or wraps a redis error reply.
+func isErrorReply(err error) bool {
+	var rerr redis.Error
+	return errors.As(err, &rerr)
+}
+
+// isNoSuchMaster checks if err is a sentinel error reply for unknown master
+// name.
+func isNoSuchMaster(err error) bool {
+	var rerr redis.Error
+	return errors.As(err, &rerr) && strings.Contains(string(rerr), "No such master")
+}
+
+// ReplicaInfo is a state of the master replica as reported by sentinel.
+type ReplicaInfo struct {
+	IP               string
+	Port             int
+	RunID            string
+	Flags            []string
+	MasterLinkStatus string
+	SlaveReplOffset  int64
+	SlavePriority    int
+	LastOKPingReply  time.Duration
+}
+
+// Addr returns replica address.
+func (r ReplicaInfo) Addr() Addr {
+	return Addr{Host: r.IP, Port: r.Port}
+}
+
+// Healthy checks if replica is usable for serving read traffic. Replica is
+// considered healthy if it is not flagged as subjectively down, objectively
+// down or disconnected and its link to the master is up.
+func (r ReplicaInfo) Healthy() bool {
+	return !flaggedDown(r.Flags) && r.MasterLinkStatus == "ok"
+}
+
+// down checks if replica is flagged down or disconnected by sentinel. Unlike
+// Healthy it ignores the state of the replica link to master.
+func (r ReplicaInfo) down() bool {
+	return flaggedDown(r.Flags)
+}
+
+// flaggedDown checks if instance flags reported by sentinel mark the instance
+// as subjectively down, objectively down or disconnected.
+func flaggedDown(flags []string) bool {
+	for _, f := range flags {
+		switch f {
+		case "s_down", "o_down", "disconnected":
+			return true
+		}
+	}
+	return false
+}
+
+// Replicas returns the state of all replicas of the named master. Older
+// sentinel servers that do not support the "replicas" subcommand are queried
+// using "slaves".
+func (sc *Client) Replicas(name string) ([]ReplicaInfo, error) {
+	return sc.replicas(context.Background(), name)
+}
+
+func (sc *Client) replicas(ctx context.Context, name string) ([]ReplicaInfo, error) {
+	res, err := redis.Values(sc.doContext(ctx, "SENTINEL", "replicas", name))
+	if isErrorReply(err) && !isNoSuchMaster(err) {
+		res, err = redis.Values(sc.doContext(ctx, "SENTINEL", "slaves", name))
+	}
+	if isNoSuchMaster(err) {
+		return nil, ErrMasterUnknown
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	replicas := make([]ReplicaInfo, 0, len(res))
+	for _, r := range res {
+		m, err := redis.StringMap(r, nil)
+		if err != nil {
+			return nil, err
+		}
+		info, err := parseReplicaInfo(m)
+		if err != nil {
+			return nil, err
+		}
+		replicas = append(replicas, info)
+	}
+	return replicas, nil
+}
+
+// ReplicaAddresses looks up the configuration for a named monitored instance
+// set and returns addresses of all its replicas not flagged as s_down, o_down
+// or disconnected. State of the replica link to master is not checked, use
+// Replicas and ReplicaInfo.Healthy to skip replicas with the link down.
+func (sc *Client) ReplicaAddresses(name string) ([]string, error) {
+	return sc.ReplicaAddressesContext(context.Background(), name)
+}
+
+// ReplicaAddressesContext is like ReplicaAddresses, but stops trying further
+// sentinels once ctx is done.
+func (sc *Client) ReplicaAddressesContext(ctx context.Context, name string) ([]string, error) {
+	replicas, err := sc.replicas(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make([]string, 0, len(replicas))
+	for _, r := range replicas {
+		if !r.down() {
+			addrs = append(addrs, r.Addr().String())
+		}
+	}
+	return addrs, nil
+}
+
+// SentinelInfo is a state of the peer sentinel monitoring the same master as
+// reported by sentinel.
+type SentinelInfo struct {
+	Name             string
+	IP               string
+	Port             int
+	RunID            string
+	Flags            []string
+	LastPingSent     time.Duration
+	LastOKPingReply  time.Duration
+	LastPingReply    time.Duration
+	LastHelloMessage time.Duration
+	VotedLeader      string
+	VotedLeaderEpoch int64
+}
+
+// Addr returns sentinel address.
+func (s SentinelInfo) Addr() Addr {
+	return Addr{Host: s.IP, Port: s.Port}
+}
+
+// Sentinels returns the state of other sentinels monitoring the named master.
+// Note that the list does not include the sentinel answering the query.
+func (sc *Client) Sentinels(name string) ([]SentinelInfo, error) {
+	res, err := redis.Values(sc.do("SENTINEL", "sentinels", name))
+	if isNoSuchMaster(err) {
+		return nil, ErrMasterUnknown
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sentinels := make([]SentinelInfo, 0, len(res))
+	for _, r := range res {
+		m, err := redis.StringMap(r, nil)
+		if err != nil {
+			return nil, err
+		}
+		info, err := parseSentinelInfo(m)
+		if err != nil {
+			return nil, err
+		}
+		sentinels = append(sentinels, info)
+	}
+	return sentinels, nil
+}
+
+func parseSentinelInfo(m map[string]string) (SentinelInfo, error) {
+	f := fields{m: m}
+	info := SentinelInfo{
+		Name:             f.str("name"),
+		IP:               f.str("ip"),
+		Port:             f.int("port"),
+		RunID:            f.str("runid"),
+		Flags:            f.flags("flags"),
+		LastPingSent:     f.ms("last-ping-sent"),
+		LastOKPingReply:  f.ms("last-ok-ping-reply"),
+		LastPingReply:    f.ms("last-ping-reply"),
+		LastHelloMessage: f.ms("last-hello-message"),
+		VotedLeader:      f.str("voted-leader"),
+		VotedLeaderEpoch: f.int64("voted-leader-epoch"),
+	}
+	return info, f.err
+}
+
+func parseReplicaInfo(m map[string]string) (ReplicaInfo, error) {
+	f := fields{m: m}
+	info := ReplicaInfo{
+		IP:               f.str("ip"),
+		Port:             f.int("port"),
+		RunID:            f.str("runid"),
+		Flags:            f.flags("flags"),
+		MasterLinkStatus: f.str("master-link-status"),
+		SlaveReplOffset:  f.int64("slave-repl-offset"),
+		SlavePriority:    f.int("slave-priority"),
+		LastOKPingReply:  f.ms("last-ok-ping-reply"),
+	}
+	return info, f.err
+}
+
+// isUnknownCommand checks if err is an error reply for an unknown command or
+// subcommand.
+func isUnknownCommand(err error) bool {
+	var rerr redis.Error
+	if !errors.As(err, &rerr) {
+		return false
+	}
+	msg := strings.ToLower(string(rerr))
+	return strings.Contains(msg, "unknown command") || strings.Contains(msg, "unknown subcommand") ||
+		strings.Contains(msg, "unknown sentinel subcommand")
+}
+
+// MyID returns the run_id of the active sentinel. ErrUnsupportedCommand is
+// returned by sentinels older than 6.2.
+func (sc *Client) MyID() (string, error) {
+	id, err := redis.String(sc.doActive("SENTINEL", "MYID"))
+	if isUnknownCommand(err) {
+		return "", ErrUnsupportedCommand
+	}
+	return id, err
+}
+
+func parseMasterInfo(m map[string]string) (MasterInfo, error) {
+	f := fields{m: m}
+	info := MasterInfo{
+		Name:              f.str("name"),
+		IP:                f.str("ip"),
+		Port:              f.int("port"),
+		Flags:             f.flags("flags"),
+		NumSlaves:         f.int("num-slaves"),
+		NumOtherSentinels: f.int("num-other-sentinels"),
+		Quorum:            f.int("quorum"),
+		LastOKPingReply:   f.ms("last-ok-ping-reply"),
+		ConfigEpoch:       f.int64("config-epoch"),
+		DownAfter:         f.ms("down-after-milliseconds"),
+		FailoverTimeout:   f.ms("failover-timeout"),
+		FailoverState:     f.str("failover-state"),
+	}
+	return info, f.err
+}
+
+// fields is a helper for parsing flat key/value replies describing sentinel
+// instances. Keys not known to the parser are ignored, missing keys are parsed
+// as zero values. First encountered parsing error is kept in err.
+type fields struct {
+	m   map[string]string
+	err error
+}
+
+func (f *fields) str(key string) string {
+	return f.m[key]
+}
+
+func (f *fields) int64(key string) int64 {
+	v, ok := f.m[key]
+	if !ok {
+		return 0
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil && f.err == nil {
+		f.err = fmt.Errorf("sentinel: invalid %s value %q", key, v)
+	}
+	return n
+}
+
+func (f *fields) int(key string) int {
+	return int(f.int64(key))
+}
+
+func (f *fields) ms(key string) time.Duration {
+	return time.Duration(f.int64(key)) * time.Millisecond
+}
+
+func (f *fields) flags(key string) []string {
+	v, ok := f.m[key]
+	if !ok || v == "" {
+		return nil
+	}
+	return strings.Split(v, ",")
+}
+
+// DownReply is sentinel's view of the master state returned by
+// IsMasterDownByAddr.
+type DownReply struct {
+	// Down is true if sentinel considers the master subjectively down.
+	Down bool
+	// LeaderRunID is the run_id of the sentinel voted as a failover leader,
+	// "*" if no vote was requested.
+	LeaderRunID string
+	// LeaderEpoch is the epoch of the leader vote.
+	LeaderEpoch int64
+}
+
+// IsMasterDownByAddr asks sentinel whether it considers the master at the
+// specified address down. No leader vote is requested, so the query does not
+// change the sentinel state. To get the view of a specific sentinel use a
+// Client configured with that single sentinel address.
+func (sc *Client) IsMasterDownByAddr(ip string, port int) (DownReply, error) {
+	res, err := redis.Values(sc.do("SENTINEL", "is-master-down-by-addr", ip, port, 0, "*"))
+	if err != nil {
+		return DownReply{}, err
+	}
+	var reply DownReply
+	var down int
+	if _, err := redis.Scan(res, &down, &reply.LeaderRunID, &reply.LeaderEpoch); err != nil {
+		return DownReply{}, err
+	}
+	reply.Down = down == 1
+	return reply, nil
+}
+
+// ServerInfo is a parsed output of the INFO command executed on sentinel. It
+// is not called SentinelInfo, as that name describes peer sentinels returned
+// by Sentinels.
+type ServerInfo struct {
+	RedisVersion string
+	RunID        string
+	TCPPort      int
+	Uptime       time.Duration
+
+	// Fields of the sentinel section.
+	SentinelMasters      int
+	Tilt                 bool
+	RunningScripts       int
+	ScriptsQueueLength   int
+	SimulateFailureFlags int
+	Masters              []MasterStatus
+
+	// Raw holds all the fields of the server and sentinel sections,
+	// including those not parsed into typed fields.
+	Raw map[string]string
+}
+
+// MasterStatus is a per master status line of the sentinel INFO section.
+type MasterStatus struct {
+	Name      string
+	Status    string
+	Address   string
+	Slaves    int
+	Sentinels int
+}
+
+// Info returns parsed server and sentinel sections of the sentinel INFO.
+func (sc *Client) Info() (ServerInfo, error) {
+	return sc.info()
+}
+
+func (sc *Client) info() (ServerInfo, error) {
+	res, err := redis.String(sc.do("INFO"))
+	if err != nil {
+		return ServerInfo{}, err
+	}
+	return parseServerInfo(res)
+}
+
+func parseServerInfo(res string) (ServerInfo, error) {
+	raw := make(map[string]string)
+	for _, line := range strings.Split(res, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.IndexByte(line, ':')
+		if i < 0 {
+			continue
+		}
+		raw[line[:i]] = line[i+1:]
+	}
+
+	f := fields{m: raw}
+	info := ServerInfo{
+		RedisVersion:         f.str("redis_version"),
+		RunID:                f.str("run_id"),
+		TCPPort:              f.int("tcp_port"),
+		Uptime:               time.Duration(f.int64("uptime_in_seconds")) * time.Second,
+		SentinelMasters:      f.int("sentinel_masters"),
+		Tilt:                 f.int("sentinel_tilt") == 1,
+		RunningScripts:       f.int("sentinel_running_scripts"),
+		ScriptsQueueLength:   f.int("sentinel_scripts_queue_length"),
+		SimulateFailureFlags: f.int("sentinel_simulate_failure_flags"),
+		Raw:                  raw,
+	}
+	if f.err != nil {
+		return ServerInfo{}, f.err
+	}
+
+	for i := 0; ; i++ {
+		line, ok := raw["master"+strconv.Itoa(i)]
+		if !ok {
+			break
+		}
+		status, err := parseMasterStatus(line)
+		if err != nil {
+			return ServerInfo{}, err
+		}
+		info.Masters = append(info.Masters, status)
+	}
+	return info, nil
+}
+
+// parseMasterStatus parses master status line, e.g.
+// "name=mymaster,status=ok,address=127.0.0.1:6379,slaves=2,sentinels=3".
+func parseMasterStatus(line string) (MasterStatus, error) {
+	m := make(map[string]string)
+	for _, kv := range strings.Split(line, ",") {
+		if i := strings.IndexByte(kv, '='); i >= 0 {
+			m[kv[:i]] = kv[i+1:]
+		}
+	}
+	f := fields{m: m}
+	status := MasterStatus{
+		Name:      f.str("name"),
+		Status:    f.str("status"),
+		Address:   f.str("address"),
+		Slaves:    f.int("slaves"),
+		Sentinels: f.int("sentinels"),
+	}
+	return status, f.err
+}
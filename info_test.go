@@ -0,0 +1,138 @@
+package sentinel
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplicaInfoHealthy(t *testing.T) {
+	tests := []struct {
+		flags   []string
+		link    string
+		healthy bool
+		down    bool
+	}{
+		{[]string{"slave"}, "ok", true, false},
+		{[]string{"slave"}, "err", false, false},
+		{[]string{"slave", "s_down"}, "ok", false, true},
+		{[]string{"slave", "o_down", "s_down"}, "ok", false, true},
+		{[]string{"slave", "disconnected"}, "ok", false, true},
+	}
+	for _, tt := range tests {
+		r := ReplicaInfo{Flags: tt.flags, MasterLinkStatus: tt.link}
+		if got := r.Healthy(); got != tt.healthy {
+			t.Errorf("%v %s: Healthy() = %v, want %v", tt.flags, tt.link, got, tt.healthy)
+		}
+		if got := r.down(); got != tt.down {
+			t.Errorf("%v: down() = %v, want %v", tt.flags, got, tt.down)
+		}
+		if got := (SentinelInfo{Flags: tt.flags}).down(); got != tt.down {
+			t.Errorf("%v: SentinelInfo down() = %v, want %v", tt.flags, got, tt.down)
+		}
+	}
+}
+
+func TestSentinels(t *testing.T) {
+	peer := func(name, ip, port, flags string) string {
+		return bulkArr("name", name, "ip", ip, "port", port, "runid", "abc"+port,
+			"flags", flags, "last-ping-sent", "0", "last-ok-ping-reply", "120",
+			"last-ping-reply", "120", "last-hello-message", "1500",
+			"voted-leader", "?", "voted-leader-epoch", "7")
+	}
+	s := newFake(t, func(args []string) string {
+		if len(args) == 3 && strings.EqualFold(args[1], "sentinels") {
+			if args[2] != "mymaster" {
+				return "-ERR No such master with that name\r\n"
+			}
+			return "*2\r\n" + peer("a1", "10.0.0.5", "26379", "sentinel") +
+				peer("b2", "10.0.0.6", "26380", "s_down,sentinel")
+		}
+		return basicHandler(args)
+	})
+	c := NewClient([]string{s.addr()})
+	defer c.Close()
+
+	got, err := c.Sentinels("mymaster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d sentinels, want 2", len(got))
+	}
+	want := SentinelInfo{
+		Name:             "a1",
+		IP:               "10.0.0.5",
+		Port:             26379,
+		RunID:            "abc26379",
+		Flags:            []string{"sentinel"},
+		LastOKPingReply:  120 * time.Millisecond,
+		LastPingReply:    120 * time.Millisecond,
+		LastHelloMessage: 1500 * time.Millisecond,
+		VotedLeader:      "?",
+		VotedLeaderEpoch: 7,
+	}
+	if !reflect.DeepEqual(got[0], want) {
+		t.Errorf("Sentinels()[0] = %+v, want %+v", got[0], want)
+	}
+	if got[1].Addr() != (Addr{Host: "10.0.0.6", Port: 26380}) || !got[1].down() {
+		t.Errorf("Sentinels()[1] = %+v, want down peer at 10.0.0.6:26380", got[1])
+	}
+
+	if _, err := c.Sentinels("other"); err != ErrMasterUnknown {
+		t.Errorf("Sentinels(other) error = %v, want ErrMasterUnknown", err)
+	}
+}
+
+func TestReplicaAddresses(t *testing.T) {
+	replica := func(port, flags, link string) string {
+		return bulkArr("ip", "10.0.0.2", "port", port, "flags", flags, "master-link-status", link)
+	}
+	s := newFake(t, func(args []string) string {
+		if len(args) > 1 && strings.EqualFold(args[1], "replicas") {
+			return "*5\r\n" + replica("6380", "slave", "ok") +
+				replica("6381", "slave", "err") +
+				replica("6382", "slave,s_down", "ok") +
+				replica("6383", "slave,o_down,s_down", "ok") +
+				replica("6384", "slave,disconnected", "ok")
+		}
+		return basicHandler(args)
+	})
+	c := NewClient([]string{s.addr()})
+	defer c.Close()
+
+	addrs, err := c.ReplicaAddresses("mymaster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Replica with master link down is not flagged by sentinel.
+	if want := []string{"10.0.0.2:6380", "10.0.0.2:6381"}; !reflect.DeepEqual(addrs, want) {
+		t.Errorf("ReplicaAddresses() = %v, want %v", addrs, want)
+	}
+}
+
+func TestIsMasterDownByAddr(t *testing.T) {
+	s := newFake(t, func(args []string) string {
+		if len(args) == 6 && strings.EqualFold(args[1], "is-master-down-by-addr") {
+			if args[2] == "10.0.0.1" && args[3] == "6379" && args[4] == "0" && args[5] == "*" {
+				return "*3\r\n:1\r\n" + bulk("*") + ":0\r\n"
+			}
+			return "*3\r\n:0\r\n" + bulk("*") + ":0\r\n"
+		}
+		return basicHandler(args)
+	})
+	c := NewClient([]string{s.addr()})
+	defer c.Close()
+
+	reply, err := c.IsMasterDownByAddr("10.0.0.1", 6379)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (DownReply{Down: true, LeaderRunID: "*"}); reply != want {
+		t.Errorf("IsMasterDownByAddr() = %+v, want %+v", reply, want)
+	}
+	if reply, err = c.IsMasterDownByAddr("10.0.0.2", 6379); err != nil || reply.Down {
+		t.Errorf("IsMasterDownByAddr() = %+v, %v, want up", reply, err)
+	}
+}
@@ -0,0 +1,131 @@
+package sentinel
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// peerListener records remote addresses of the accepted connections.
+type peerListener struct {
+	net.Listener
+	peers chan net.Addr
+}
+
+func (l peerListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err == nil {
+		l.peers <- c.RemoteAddr()
+	}
+	return c, err
+}
+
+// newPeerFake starts a fake server reporting addresses of the accepted
+// connections over the returned channel.
+func newPeerFake(t *testing.T, h func(args []string) string) (*fakeSentinel, <-chan net.Addr) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	peers := make(chan net.Addr, 16)
+	return serveFake(t, peerListener{Listener: ln, peers: peers}, h, 0), peers
+}
+
+// socketKeepAlive returns TCP keep-alive setting of the socket bound to
+// local address addr in this process.
+func socketKeepAlive(t *testing.T, addr net.Addr) (enabled bool, idle time.Duration) {
+	t.Helper()
+	port := addr.(*net.TCPAddr).Port
+	for fd := 0; fd < 4096; fd++ {
+		sa, err := syscall.Getsockname(fd)
+		if err != nil {
+			continue
+		}
+		if in, ok := sa.(*syscall.SockaddrInet4); !ok || in.Port != port {
+			continue
+		}
+		on, err := syscall.GetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_KEEPALIVE)
+		if err != nil {
+			t.Fatal(err)
+		}
+		secs, err := syscall.GetsockoptInt(fd, syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return on != 0, time.Duration(secs) * time.Second
+	}
+	t.Fatalf("socket bound to %s not found", addr)
+	return false, 0
+}
+
+func receivePeer(t *testing.T, peers <-chan net.Addr) net.Addr {
+	t.Helper()
+	select {
+	case addr := <-peers:
+		return addr
+	case <-time.After(time.Second):
+		t.Fatal("no connection accepted")
+	}
+	return nil
+}
+
+func TestKeepAlive(t *testing.T) {
+	tests := []struct {
+		name      string
+		keepAlive time.Duration
+		enabled   bool
+		idle      time.Duration
+	}{
+		{"zero", 0, false, 0},
+		{"NoTimeout", NoTimeout, false, 0},
+		{"period", 7 * time.Second, true, 7 * time.Second},
+	}
+	for _, tt := range tests {
+		master, masterPeers := newPeerFake(t, roleMaster)
+		s, sentinelPeers := newPeerFake(t, pointTo(master.addr()))
+		conf := testConfig(s.addr())
+		conf.SentinelKeepAlive, conf.RedisKeepAlive = tt.keepAlive, tt.keepAlive
+		p, err := NewPool(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := p.DialContext(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, conn := range []struct {
+			kind  string
+			peers <-chan net.Addr
+		}{{"sentinel", sentinelPeers}, {"redis", masterPeers}} {
+			enabled, idle := socketKeepAlive(t, receivePeer(t, conn.peers))
+			if enabled != tt.enabled || (tt.enabled && idle != tt.idle) {
+				t.Errorf("%s: %s keep-alive enabled=%v idle=%v, want enabled=%v idle=%v",
+					tt.name, conn.kind, enabled, idle, tt.enabled, tt.idle)
+			}
+		}
+		c.Close()
+		p.Close()
+	}
+}
+
+func TestKeepAliveDefaults(t *testing.T) {
+	master, peers := newPeerFake(t, roleMaster)
+	s := newFake(t, pointTo(master.addr()))
+	p, err := NewPoolWithOptions("mymaster", []string{s.addr()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	c, err := p.DialContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if enabled, idle := socketKeepAlive(t, receivePeer(t, peers)); !enabled || idle != DefaultKeepAlive {
+		t.Errorf("redis keep-alive enabled=%v idle=%v, want %v", enabled, idle, DefaultKeepAlive)
+	}
+}
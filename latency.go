@@ -0,0 +1,158 @@
+package sentinel
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	// latencyHysteresis is the relative improvement over the active sentinel
+	// latency required to prefer another sentinel, so sentinels of similar
+	// latency do not take turns being active.
+	latencyHysteresis = 0.2
+	// latencySmoothing is the weight of the newest sample in the moving
+	// average of sentinel latency.
+	latencySmoothing = 0.3
+)
+
+// latencyProber periodically measures PING round trip time of every sentinel
+// and ranks the sentinels so the fastest healthy one is preferred.
+type latencyProber struct {
+	interval time.Duration
+	cancel   context.CancelFunc
+	// conns are probe connections by sentinel address, owned by the probe
+	// goroutine.
+	conns map[string]redis.Conn
+}
+
+// startProber starts latency probing if enabled by WithLatencyProbing.
+func (sc *Client) startProber() {
+	if sc.prober == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	sc.prober.cancel = cancel
+	sc.prober.conns = make(map[string]redis.Conn)
+	go sc.prober.run(ctx, sc)
+}
+
+func (p *latencyProber) run(ctx context.Context, sc *Client) {
+	defer func() {
+		for _, c := range p.conns {
+			c.Close()
+		}
+	}()
+
+	t := time.NewTicker(p.interval)
+	defer t.Stop()
+	for {
+		p.probe(ctx, sc)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// probe measures latency of all the sentinels and ranks them.
+func (p *latencyProber) probe(ctx context.Context, sc *Client) {
+	sc.Lock()
+	addrs := append([]string(nil), sc.addrs...)
+	options, name := sc.options, sc.clientName
+	sc.Unlock()
+
+	for _, addr := range addrs {
+		if ctx.Err() != nil {
+			return
+		}
+		rtt, err := p.ping(ctx, addr, options, name)
+
+		sc.Lock()
+		st := sc.sentinelState(addr)
+		st.latencyChecked = time.Now()
+		if err != nil {
+			st.latency = 0
+			st.latencyErr = err
+		} else {
+			if st.latencyErr != nil || st.latency == 0 {
+				st.latency = rtt
+			} else {
+				st.latency = time.Duration(latencySmoothing*float64(rtt) + (1-latencySmoothing)*float64(st.latency))
+			}
+			st.latencyErr = nil
+		}
+		sc.Unlock()
+	}
+
+	sc.rankByLatency()
+}
+
+// ping sends PING to the sentinel at addr and returns the round trip time.
+// Probe connection is dialed if necessary and closed on failure.
+func (p *latencyProber) ping(ctx context.Context, addr string, options []redis.DialOption, name string) (time.Duration, error) {
+	c, ok := p.conns[addr]
+	if !ok {
+		var err error
+		if c, err = dialSentinel(ctx, addr, options, name); err != nil {
+			return 0, err
+		}
+		p.conns[addr] = c
+	}
+
+	start := time.Now()
+	if _, err := redis.DoContext(c, ctx, "PING"); err != nil {
+		c.Close()
+		delete(p.conns, addr)
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
+// rankByLatency ranks sentinels by measured latency, the ones that failed the
+// last probe are ranked last. The configured order in addrs is left intact.
+// The fastest sentinel is made active unless the active one is healthy and
+// not significantly slower.
+func (sc *Client) rankByLatency() {
+	sc.Lock()
+	defer sc.Unlock()
+
+	healthy := func(addr string) bool {
+		st := sc.sentinelState(addr)
+		return st.latencyErr == nil && st.latency > 0
+	}
+
+	if len(sc.addrs) == 0 {
+		return
+	}
+	addrs := append([]string(nil), sc.order()...)
+	sort.SliceStable(addrs, func(i, j int) bool {
+		hi, hj := healthy(addrs[i]), healthy(addrs[j])
+		if hi != hj {
+			return hi
+		}
+		return hi && sc.state[addrs[i]].latency < sc.state[addrs[j]].latency
+	})
+
+	// Keep the active sentinel first unless the fastest one is significantly
+	// faster.
+	active := sc.order()[sc.activeAddr]
+	if healthy(active) && active != addrs[0] {
+		fastest := sc.state[addrs[0]].latency
+		if float64(fastest) > (1-latencyHysteresis)*float64(sc.state[active].latency) {
+			for i, addr := range addrs {
+				if addr == active {
+					copy(addrs[1:i+1], addrs[:i])
+					addrs[0] = active
+					break
+				}
+			}
+		}
+	}
+
+	sc.ranked = addrs
+	sc.activeAddr = 0
+}
@@ -0,0 +1,95 @@
+package sentinel
+
+import (
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// latencyFakes starts sentinels a, b and c, delaying PING replies of a and
+// b, so their latency ranking is c, b, a. Sentinel a fails lookups while
+// fail is set.
+func latencyFakes(t *testing.T, fail *int32) (a, b, c *fakeSentinel) {
+	slow := func(delay time.Duration, h func([]string) string) func([]string) string {
+		return func(args []string) string {
+			if strings.EqualFold(args[0], "PING") {
+				time.Sleep(delay)
+			}
+			return h(args)
+		}
+	}
+	a = newFake(t, slow(40*time.Millisecond, func(args []string) string {
+		if strings.EqualFold(args[0], "SENTINEL") && atomic.CompareAndSwapInt32(fail, 1, 0) {
+			return "-ERR transient\r\n"
+		}
+		return basicHandler(args)
+	}))
+	b = newFake(t, slow(20*time.Millisecond, basicHandler))
+	c = newFake(t, basicHandler)
+	return a, b, c
+}
+
+func TestLatencyProbing(t *testing.T) {
+	var fail int32
+	a, b, c := latencyFakes(t, &fail)
+	configured := []string{a.addr(), b.addr(), c.addr()}
+	sc := NewClientWithOptions(configured, WithLatencyProbing(time.Hour))
+	defer sc.Close()
+
+	waitFor(t, "fastest sentinel to become active", func() bool { return sc.ActiveSentinel() == c.addr() })
+	if got := sc.Addrs(); !reflect.DeepEqual(got, configured) {
+		t.Errorf("Addrs() = %v, want configured order %v", got, configured)
+	}
+	var ranked []string
+	for _, st := range sc.Stats() {
+		ranked = append(ranked, st.Addr)
+	}
+	if want := []string{c.addr(), b.addr(), a.addr()}; !reflect.DeepEqual(ranked, want) {
+		t.Errorf("Stats() order = %v, want latency ranking %v", ranked, want)
+	}
+
+	if _, err := sc.MasterAddress("mymaster"); err != nil {
+		t.Fatal(err)
+	}
+	if n := c.calls(getMaster); n != 1 {
+		t.Errorf("fastest sentinel queried %d times, want 1", n)
+	}
+}
+
+func TestLatencyProbingPreferFirst(t *testing.T) {
+	var fail int32
+	a, b, c := latencyFakes(t, &fail)
+	configured := []string{a.addr(), b.addr(), c.addr()}
+	sc := NewClientWithOptions(configured, WithLatencyProbing(time.Hour), WithStickiness(StickinessPreferFirst))
+	defer sc.Close()
+
+	waitFor(t, "fastest sentinel to become active", func() bool { return sc.ActiveSentinel() == c.addr() })
+	if _, err := sc.MasterAddress("mymaster"); err != nil {
+		t.Fatal(err)
+	}
+	// Prefer-first returns to the first configured sentinel, not to the
+	// fastest one.
+	if got := sc.ActiveSentinel(); got != a.addr() {
+		t.Errorf("active sentinel %s after lookup, want first configured %s", got, a.addr())
+	}
+
+	// Once the first sentinel fails the next one is taken by latency.
+	atomic.StoreInt32(&fail, 1)
+	if _, err := sc.MasterAddress("mymaster"); err != nil {
+		t.Fatal(err)
+	}
+	if n := c.calls(getMaster); n != 2 {
+		t.Errorf("fastest sentinel queried %d times, want 2", n)
+	}
+	if n := b.calls(getMaster); n != 0 {
+		t.Errorf("second configured sentinel queried %d times, want 0", n)
+	}
+	if got := sc.ActiveSentinel(); got != a.addr() {
+		t.Errorf("active sentinel %s after failover, want first configured %s", got, a.addr())
+	}
+	if got := sc.Addrs(); !reflect.DeepEqual(got, configured) {
+		t.Errorf("Addrs() = %v, want configured order %v", got, configured)
+	}
+}
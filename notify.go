@@ -0,0 +1,176 @@
+package sentinel
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// masterPollInterval is the interval of master address lookups used to detect
+// master changes when sentinel pub/sub is not available.
+const masterPollInterval = time.Second
+
+// masterNotifier invokes callbacks registered with OnMasterChange.
+type masterNotifier struct {
+	sc     *Client
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// startMu serializes starting the watching of masters, which involves
+	// network round trips, so it is done without holding mu. It guards
+	// watcher as well.
+	startMu sync.Mutex
+	watcher *MasterWatcher
+
+	mu      sync.Mutex
+	masters map[string]*notifiedMaster
+	nextID  uint64
+}
+
+// notifiedMaster holds the callbacks registered for a single master.
+type notifiedMaster struct {
+	callbacks map[uint64]func(old, new string)
+	// stop stops watching the master, it is nil until watching is started.
+	stop func()
+}
+
+// OnMasterChange registers fn to be called every time the address of the
+// named master changes. Changes are detected by watching +switch-master
+// events, falling back to polling the master address if none of the sentinels
+// can be subscribed to. Callbacks are called without holding the Client lock,
+// so they can use the Client. Returned function deregisters the callback.
+// Closing the Client stops all the notifications.
+func (sc *Client) OnMasterChange(name string, fn func(old, new string)) (cancel func()) {
+	sc.Lock()
+	if sc.notifier == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		sc.notifier = &masterNotifier{
+			sc:      sc,
+			ctx:     ctx,
+			cancel:  cancel,
+			masters: make(map[string]*notifiedMaster),
+		}
+	}
+	n := sc.notifier
+	sc.Unlock()
+
+	n.mu.Lock()
+	id := n.nextID
+	n.nextID++
+	m := n.masters[name]
+	first := m == nil
+	if first {
+		m = &notifiedMaster{callbacks: make(map[uint64]func(old, new string))}
+		n.masters[name] = m
+	}
+	m.callbacks[id] = fn
+	n.mu.Unlock()
+
+	if first {
+		n.start(name, m)
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { n.remove(name, id) })
+	}
+}
+
+// start starts watching the named master for the callbacks in m. Watching is
+// stopped right away if all of the callbacks were removed in the meantime.
+func (n *masterNotifier) start(name string, m *notifiedMaster) {
+	n.startMu.Lock()
+	defer n.startMu.Unlock()
+
+	stop := n.watch(name)
+	n.mu.Lock()
+	if n.masters[name] == m {
+		m.stop, stop = stop, nil
+	}
+	n.mu.Unlock()
+	if stop != nil {
+		stop()
+	}
+}
+
+// watch starts watching the named master and returns function stopping it.
+// It must be called with n.startMu held.
+func (n *masterNotifier) watch(name string) func() {
+	if n.watcher == nil {
+		if w, err := n.sc.WatchMasters(n.ctx); err == nil {
+			n.watcher = w
+		}
+	}
+	if n.watcher != nil {
+		ch := n.watcher.Add(name)
+		go func() {
+			for ev := range ch {
+				n.notify(name, ev.Old.String(), ev.New.String())
+			}
+		}()
+		w := n.watcher
+		return func() { w.Remove(name) }
+	}
+
+	ctx, cancel := context.WithCancel(n.ctx)
+	go n.poll(ctx, name)
+	return cancel
+}
+
+// poll looks up the named master address periodically, notifying callbacks
+// when it changes.
+func (n *masterNotifier) poll(ctx context.Context, name string) {
+	last, _ := n.sc.MasterAddressContext(ctx, name)
+
+	t := time.NewTicker(masterPollInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		addr, err := n.sc.MasterAddressContext(ctx, name)
+		if err != nil {
+			continue
+		}
+		if last != "" && addr != last {
+			n.notify(name, last, addr)
+		}
+		last = addr
+	}
+}
+
+// notify calls all the callbacks registered for the named master.
+func (n *masterNotifier) notify(name, old, new string) {
+	n.mu.Lock()
+	var fns []func(old, new string)
+	if m := n.masters[name]; m != nil {
+		fns = make([]func(old, new string), 0, len(m.callbacks))
+		for _, fn := range m.callbacks {
+			fns = append(fns, fn)
+		}
+	}
+	n.mu.Unlock()
+
+	for _, fn := range fns {
+		fn(old, new)
+	}
+}
+
+// remove deregisters callback, watching of the master is stopped once there
+// are no callbacks left. Stopping does not block, so it is done with n.mu held
+// to ensure it completes before the master can be watched again.
+func (n *masterNotifier) remove(name string, id uint64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	m := n.masters[name]
+	delete(m.callbacks, id)
+	if len(m.callbacks) == 0 {
+		delete(n.masters, name)
+		if m.stop != nil {
+			m.stop()
+		}
+	}
+}
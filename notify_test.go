@@ -0,0 +1,105 @@
+package sentinel
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// receiveChange waits for an OnMasterChange callback.
+func receiveChange(t *testing.T, ch <-chan [2]string) [2]string {
+	t.Helper()
+	select {
+	case change := <-ch:
+		return change
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for master change")
+	}
+	return [2]string{}
+}
+
+func TestOnMasterChange(t *testing.T) {
+	release := make(chan struct{})
+	s := newFake(t, func(args []string) string {
+		if strings.EqualFold(args[0], "PSUBSCRIBE") {
+			// Switch is announced once the callback is registered.
+			select {
+			case <-release:
+			case <-time.After(5 * time.Second):
+			}
+			return subscribed + switchMessage("mymaster", 6379, 6380) + switchMessage("mymaster", 6380, 6381)
+		}
+		return basicHandler(args)
+	})
+	c := NewClient([]string{s.addr()})
+	defer c.Close()
+
+	changes := make(chan [2]string, 2)
+	cancel := c.OnMasterChange("mymaster", func(old, new string) {
+		changes <- [2]string{old, new}
+	})
+	defer cancel()
+	close(release)
+
+	if got, want := receiveChange(t, changes), [2]string{"10.0.0.1:6379", "10.0.0.1:6380"}; got != want {
+		t.Errorf("change = %v, want %v", got, want)
+	}
+	if got, want := receiveChange(t, changes), [2]string{"10.0.0.1:6380", "10.0.0.1:6381"}; got != want {
+		t.Errorf("change = %v, want %v", got, want)
+	}
+}
+
+func TestOnMasterChangeSetupUnlocked(t *testing.T) {
+	announce := make(chan struct{})
+	lookup := make(chan struct{})
+	s := newFake(t, func(args []string) string {
+		switch {
+		case strings.EqualFold(args[0], "PSUBSCRIBE"):
+			select {
+			case <-announce:
+			case <-time.After(5 * time.Second):
+			}
+			return subscribed + switchMessage("mymaster", 6379, 6380)
+		case len(args) > 2 && args[2] == "other":
+			// Registration of the other master hangs looking it up.
+			select {
+			case <-lookup:
+			case <-time.After(5 * time.Second):
+			}
+			return bulkArr("10.0.0.2", "6379")
+		}
+		return basicHandler(args)
+	})
+	c := NewClient([]string{s.addr()})
+	defer c.Close()
+
+	changes := make(chan [2]string, 1)
+	cancel := c.OnMasterChange("mymaster", func(old, new string) {
+		changes <- [2]string{old, new}
+	})
+	registered := make(chan func())
+	go func() {
+		registered <- c.OnMasterChange("other", func(old, new string) {})
+	}()
+	waitFor(t, "other master lookup", func() bool {
+		return s.calls("SENTINEL get-master-addr-by-name other") > 0
+	})
+
+	close(announce)
+	if got, want := receiveChange(t, changes), [2]string{"10.0.0.1:6379", "10.0.0.1:6380"}; got != want {
+		t.Errorf("change = %v, want %v", got, want)
+	}
+	removed := make(chan struct{})
+	go func() {
+		cancel()
+		close(removed)
+	}()
+	select {
+	case <-removed:
+	case <-time.After(time.Second):
+		t.Fatal("callback removal blocked by registration in progress")
+	}
+
+	close(lookup)
+	(<-registered)()
+}
@@ -0,0 +1,333 @@
+package sentinel
+
+import (
+	"context"
+	"crypto/tls"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// ClientOption configures Client created with NewClientWithOptions.
+type ClientOption func(*Client)
+
+// WithDialOptions sets dial options used when connecting to the sentinel
+// servers.
+func WithDialOptions(options ...redis.DialOption) ClientOption {
+	return func(sc *Client) {
+		sc.options = append(sc.options, options...)
+	}
+}
+
+// WithAuth sets credentials used to authenticate to the sentinel servers.
+// Empty username authenticates as the default user using password only.
+// Queries rejected due to credentials are not retried on other sentinels and
+// fail with *AuthError.
+func WithAuth(username, password string) ClientOption {
+	return func(sc *Client) {
+		if username != "" {
+			sc.options = append(sc.options, redis.DialUsername(username))
+		}
+		if password != "" {
+			sc.options = append(sc.options, redis.DialPassword(password))
+		}
+	}
+}
+
+// DialFunc dials network connection to the address, e.g. through a proxy or
+// from a specific local address.
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// WithDialer makes Client connect to the sentinel servers using dial instead
+// of the default net.Dialer. Connect timeout and keep-alive dial options do
+// not apply to such connections.
+func WithDialer(dial DialFunc) ClientOption {
+	return func(sc *Client) {
+		if dial != nil {
+			sc.options = append(sc.options, redis.DialContextFunc(dial))
+		}
+	}
+}
+
+// WithHostnames selects how master hostnames announced by sentinels are
+// handled, see HostnameMode. Resolver is used with HostnameResolve mode, nil
+// uses net.DefaultResolver.
+func WithHostnames(mode HostnameMode, resolver *net.Resolver) ClientOption {
+	return func(sc *Client) {
+		sc.hostnames = mode
+		sc.resolver = resolver
+	}
+}
+
+// WithSRVRefresh makes Client look up sentinels given as srv:// addresses
+// again in the specified interval, so sentinels added to or removed from DNS
+// are picked up. SRV records are looked up once when Client is created with
+// zero interval. Sentinels are looked up using the resolver set by
+// WithHostnames.
+func WithSRVRefresh(interval time.Duration) ClientOption {
+	return func(sc *Client) {
+		if sc.srv != nil {
+			sc.srv.interval = interval
+		}
+	}
+}
+
+// WithSentinelConnMaxAge closes sentinel connections once they are older
+// than maxAge, instead of returning them to the idle pool, so long running
+// processes dial sentinels again from time to time and pick up changes of
+// sentinel addresses behind stable hostnames.
+func WithSentinelConnMaxAge(maxAge time.Duration) ClientOption {
+	return func(sc *Client) {
+		sc.connMaxAge = maxAge
+	}
+}
+
+// WithReresolve makes Client resolve sentinel hostnames in the specified
+// interval using the resolver set by WithHostnames. Once IP addresses of a
+// sentinel change, its idle connections are closed and connections in use
+// are closed when the query is done, so the next query dials the sentinel
+// again.
+func WithReresolve(interval time.Duration) ClientOption {
+	return func(sc *Client) {
+		if interval > 0 {
+			sc.reresolver = &reresolver{interval: interval}
+		}
+	}
+}
+
+// WithSentinelDiscovery makes Client ask sentinels in the specified interval
+// about the other sentinels monitoring the named master and add them to the
+// list of sentinels tried, so Client keeps working once the configured
+// sentinels are gone. Discovered sentinels are kept until Client is closed,
+// unless dropAfter is set, in which case discovered sentinels reported down
+// for longer than dropAfter are removed. Configured sentinels are never
+// removed. See Client Addrs for the resulting list.
+func WithSentinelDiscovery(master string, interval, dropAfter time.Duration) ClientOption {
+	return func(sc *Client) {
+		if interval > 0 {
+			sc.peers = &peerDiscovery{master: master, interval: interval, dropAfter: dropAfter}
+		}
+	}
+}
+
+// WithShuffledSentinels randomizes the order of the sentinels tried by
+// Client, so the lookups of many clients configured with the same sentinel
+// list are spread across the sentinels. Sentinels are tried in the
+// configured order by default.
+func WithShuffledSentinels() ClientOption {
+	return func(sc *Client) {
+		sc.shuffle = true
+	}
+}
+
+// WithMasterDownCheck makes Client look up master addresses with SENTINEL
+// master instead of get-master-addr-by-name, to fail with *MasterDownError
+// instead of returning the address of a master flagged s_down or o_down, or
+// being failed over. The reply is larger, so the check is disabled by
+// default. Lookups with WithQuorum are not checked.
+func WithMasterDownCheck() ClientOption {
+	return func(sc *Client) {
+		sc.downCheck = true
+	}
+}
+
+// WithStickiness sets the policy of choosing the sentinel queried first
+// after a successful query. StickinessSticky is used by default, in which
+// case Client keeps querying the same sentinel until it fails.
+func WithStickiness(policy Stickiness) ClientOption {
+	return func(sc *Client) {
+		sc.stickiness = policy
+	}
+}
+
+// WithClientName sets name of sentinel connections with CLIENT SETNAME when
+// they are dialed, to identify them in sentinel CLIENT LIST. The name must
+// not contain spaces. Sentinels older than 5.0 or with CLIENT command
+// renamed are left unnamed.
+func WithClientName(name string) ClientOption {
+	return func(sc *Client) {
+		sc.clientName = name
+	}
+}
+
+// WithTLS makes Client connect to the sentinel servers over TLS configured by
+// conf. Nil conf uses the default TLS configuration, with the server name
+// taken from the sentinel address.
+func WithTLS(conf *tls.Config) ClientOption {
+	return func(sc *Client) {
+		sc.options = append(sc.options, redis.DialUseTLS(true))
+		if conf != nil {
+			sc.options = append(sc.options, redis.DialTLSConfig(conf))
+		}
+	}
+}
+
+// WithDangerousCommands allows Client to issue commands intended for testing
+// only, such as SimulateFailure, which can crash the sentinel server.
+func WithDangerousCommands() ClientOption {
+	return func(sc *Client) {
+		sc.allowDangerous = true
+	}
+}
+
+// WithTotalTimeout limits the time a single lookup may spend trying the
+// configured sentinels. Once it elapses no further sentinels are tried and
+// *TimeoutError is returned. Zero means no limit, in which case a lookup may
+// take up to (# sentinels) * (connect + read + write) timeouts.
+func WithTotalTimeout(timeout time.Duration) ClientOption {
+	return func(sc *Client) {
+		sc.totalTimeout = timeout
+	}
+}
+
+// WithMasterCache enables caching of the resolved master addresses. Master
+// address lookups are served from the cache for the ttl after a successful
+// lookup, unless invalidated with Client.Invalidate. Zero ttl disables
+// caching.
+func WithMasterCache(ttl time.Duration) ClientOption {
+	return func(sc *Client) {
+		sc.cacheTTL = ttl
+	}
+}
+
+// WithMaxConns limits the number of sentinel connections Client uses to
+// execute queries concurrently. Queries wait for a free connection once the
+// limit is reached. Defaults to 3.
+func WithMaxConns(n int) ClientOption {
+	return func(sc *Client) {
+		if n > 0 {
+			sc.maxConns = n
+		}
+	}
+}
+
+// WithHedgedRequests makes master address lookups query up to n sentinels
+// concurrently. Lookup starts with the active sentinel, the next one is
+// queried if there is no reply within delay or as soon as a query fails. The
+// first successful reply is returned and the remaining queries are cancelled.
+// Values of n less than 2 disable hedging.
+func WithHedgedRequests(n int, delay time.Duration) ClientOption {
+	return func(sc *Client) {
+		sc.hedge = n
+		sc.hedgeDelay = delay
+	}
+}
+
+// WithQuorumAgreement makes master address lookups query all the configured
+// sentinels and return the address only if the majority of them agree on it.
+// *DisagreementError listing the answers of every sentinel is returned
+// otherwise. It protects against dialing a stale master reported by a lagging
+// or partitioned sentinel, at the cost of querying all the sentinels. It takes
+// precedence over WithHedgedRequests.
+func WithQuorumAgreement() ClientOption {
+	return func(sc *Client) {
+		sc.quorum = true
+	}
+}
+
+// WithLatencyProbing enables measuring PING round trip time of every sentinel
+// each interval. Sentinels are tried in the order of measured latency, the
+// ones failing to reply are tried last. The active sentinel is replaced only
+// by a significantly faster one, to avoid flapping between sentinels of
+// similar latency. The ranking is kept apart from the configured order, which
+// is still reported by Addrs and returned to by StickinessPreferFirst.
+// Probing stops once the Client is closed. Non-positive interval disables
+// probing.
+func WithLatencyProbing(interval time.Duration) ClientOption {
+	return func(sc *Client) {
+		if interval > 0 {
+			sc.prober = &latencyProber{interval: interval}
+		} else {
+			sc.prober = nil
+		}
+	}
+}
+
+// WithCircuitBreaker makes Client skip a sentinel for the cooldown once
+// queries to it fail threshold times in a row, so a sentinel that is down
+// does not cost a connect timeout on every query. After the cooldown a single
+// query is sent to the sentinel to probe it, the sentinel is skipped for
+// another cooldown if it fails. Sentinels in cooldown are still tried when
+// all the other sentinels fail. Error replies are not counted as failures.
+// Non-positive threshold disables the circuit breaker.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) ClientOption {
+	return func(sc *Client) {
+		sc.breakerThreshold = threshold
+		sc.breakerCooldown = cooldown
+	}
+}
+
+// RetryBackoff configures delays between attempts on consecutive sentinels
+// within a single query, see WithRetryBackoff.
+type RetryBackoff struct {
+	// Base is the delay before the second attempt.
+	Base time.Duration
+	// Multiplier grows the delay before every next attempt, values below 1
+	// are treated as 1.
+	Multiplier float64
+	// Jitter is the fraction of the delay randomized, between 0 and 1.
+	Jitter float64
+	// Max caps the delay, zero means no cap.
+	Max time.Duration
+}
+
+// delay returns the delay before the attempt following the given number of
+// failed attempts.
+func (b RetryBackoff) delay(failed int) time.Duration {
+	if b.Base <= 0 || failed < 1 {
+		return 0
+	}
+	mult := b.Multiplier
+	if mult < 1 {
+		mult = 1
+	}
+	d := float64(b.Base)
+	for i := 1; i < failed; i++ {
+		d *= mult
+		if b.Max > 0 && d >= float64(b.Max) {
+			break
+		}
+	}
+	if b.Max > 0 && d > float64(b.Max) {
+		d = float64(b.Max)
+	}
+	if j := b.Jitter; j > 0 {
+		if j > 1 {
+			j = 1
+		}
+		d -= j * d * rand.Float64()
+	}
+	return time.Duration(d)
+}
+
+// WithRetryBackoff makes Client wait between attempts on consecutive
+// sentinels within a single query, so a full sentinel outage does not turn
+// into a tight loop of connection attempts. No delay is applied by default.
+// The wait is cut short once the query context is done or the total timeout
+// elapses.
+func WithRetryBackoff(b RetryBackoff) ClientOption {
+	return func(sc *Client) {
+		sc.retryBackoff = b
+	}
+}
+
+// WithLookupRateLimit limits master address lookups reaching sentinels to
+// perSecond lookups of every master name, allowing bursts of up to burst
+// lookups. It protects sentinels from the flood of lookups by pools dialing
+// an unreachable master. Lookups exceeding the limit are handled as selected
+// by mode. Lookups served from the cache are not limited. Non-positive
+// perSecond disables rate limiting.
+func WithLookupRateLimit(perSecond float64, burst int, mode RateLimitMode) ClientOption {
+	return func(sc *Client) {
+		if perSecond <= 0 {
+			sc.limiter = nil
+			return
+		}
+		if burst < 1 {
+			burst = 1
+		}
+		sc.limiter = &lookupLimiter{rate: perSecond, burst: burst, mode: mode}
+	}
+}
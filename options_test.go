@@ -0,0 +1,122 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingDialer dials connections recording the addresses dialed.
+type recordingDialer struct {
+	mu    sync.Mutex
+	addrs []string
+}
+
+func (d *recordingDialer) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	d.mu.Lock()
+	d.addrs = append(d.addrs, addr)
+	d.mu.Unlock()
+	return (&net.Dialer{}).DialContext(ctx, network, addr)
+}
+
+func (d *recordingDialer) dialed() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.addrs...)
+}
+
+func TestWithDialer(t *testing.T) {
+	s := newFake(t, basicHandler)
+	var d recordingDialer
+	c := NewClientWithOptions([]string{s.addr()}, WithDialer(d.dial))
+	defer c.Close()
+
+	if _, err := c.MasterAddress("mymaster"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := d.dialed(), []string{s.addr()}; !reflect.DeepEqual(got, want) {
+		t.Errorf("dialed %q, want %q", got, want)
+	}
+}
+
+func TestWithDialers(t *testing.T) {
+	master := newFake(t, roleMaster)
+	host, port, _ := net.SplitHostPort(master.addr())
+	// Sentinel announces the internal address, translated to the one of the
+	// fake master.
+	s := newFake(t, pointTo("10.9.9.9:6379"))
+	var sentinels, redises recordingDialer
+	p, err := NewPoolWithOptions("mymaster", []string{s.addr()},
+		WithDialers(sentinels.dial, redises.dial),
+		WithAddressTranslator(func(addr Addr) Addr {
+			if addr != (Addr{Host: "10.9.9.9", Port: 6379}) {
+				t.Errorf("translating unexpected address %v", addr)
+			}
+			n, _ := strconv.Atoi(port)
+			return Addr{Host: host, Port: n}
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	c, err := p.DialContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if got, want := sentinels.dialed(), []string{s.addr()}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sentinel dialer dialed %q, want %q", got, want)
+	}
+	if got, want := redises.dialed(), []string{master.addr()}; !reflect.DeepEqual(got, want) {
+		t.Errorf("redis dialer dialed %q, want %q", got, want)
+	}
+}
+
+func TestRetryBackoffDelay(t *testing.T) {
+	b := RetryBackoff{Base: 10 * time.Millisecond, Multiplier: 2, Max: 30 * time.Millisecond}
+	for failed, want := range []time.Duration{0, 10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond, 30 * time.Millisecond} {
+		if got := b.delay(failed); got != want {
+			t.Errorf("delay(%d) = %v, want %v", failed, got, want)
+		}
+	}
+
+	b.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if d := b.delay(1); d < 5*time.Millisecond || d > 10*time.Millisecond {
+			t.Fatalf("delay(1) = %v with jitter, want between 5ms and 10ms", d)
+		}
+	}
+}
+
+func TestWithRetryBackoff(t *testing.T) {
+	s := newFake(t, basicHandler)
+	backoff := RetryBackoff{Base: 20 * time.Millisecond, Multiplier: 2}
+	c := NewClientWithOptions([]string{closedAddr(t), closedAddr(t), s.addr()}, WithRetryBackoff(backoff))
+	defer c.Close()
+
+	start := time.Now()
+	if _, err := c.MasterAddress("mymaster"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("MasterAddress() took %v, want backoff of 20ms and 40ms between attempts", elapsed)
+	}
+
+	// The wait is cut short once the context is done.
+	c.SetAddrs([]string{closedAddr(t), closedAddr(t)})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer time.AfterFunc(10*time.Millisecond, cancel).Stop()
+	start = time.Now()
+	if _, err := c.MasterAddressContext(ctx, "mymaster"); !errors.Is(err, context.Canceled) {
+		t.Errorf("MasterAddressContext() error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 20*time.Millisecond {
+		t.Errorf("MasterAddressContext() took %v, want backoff cut short", elapsed)
+	}
+}
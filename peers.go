@@ -0,0 +1,114 @@
+package sentinel
+
+import (
+	"context"
+	"time"
+)
+
+// peerDiscovery periodically learns about other sentinels monitoring the
+// master, see WithSentinelDiscovery.
+type peerDiscovery struct {
+	master    string
+	interval  time.Duration
+	dropAfter time.Duration
+	cancel    context.CancelFunc
+	// found are the discovered sentinel addresses by run ID, protected by
+	// the Client mutex.
+	found map[string]string
+}
+
+// startPeerDiscovery starts discovering sentinels if enabled by
+// WithSentinelDiscovery.
+func (sc *Client) startPeerDiscovery() {
+	p := sc.peers
+	if p == nil {
+		return
+	}
+	p.found = make(map[string]string)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	go func() {
+		t := time.NewTicker(p.interval)
+		defer t.Stop()
+		for {
+			sc.discoverPeers()
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+		}
+	}()
+}
+
+// discoverPeers asks sentinel about the other sentinels monitoring the
+// master and adds the ones not known yet to the sentinel list. Discovered
+// sentinels down for longer than dropAfter are removed.
+func (sc *Client) discoverPeers() {
+	p := sc.peers
+	peers, err := sc.Sentinels(p.master)
+	if err != nil {
+		return
+	}
+
+	sc.Lock()
+	defer sc.Unlock()
+
+	addrs := append([]string(nil), sc.addrs...)
+	known := make(map[string]bool, len(addrs))
+	for _, addr := range addrs {
+		known[addr] = true
+	}
+	drop := make(map[string]bool)
+	for _, peer := range peers {
+		if peer.RunID == "" {
+			continue
+		}
+		addr := peer.Addr().String()
+		if old, ok := p.found[peer.RunID]; ok && old != addr {
+			// Sentinel moved to a new address.
+			drop[old] = true
+		}
+		if p.dropAfter > 0 && peer.down() && peer.LastOKPingReply > p.dropAfter {
+			if _, ok := p.found[peer.RunID]; ok {
+				drop[addr] = true
+				delete(p.found, peer.RunID)
+			}
+			continue
+		}
+		if !known[addr] {
+			known[addr] = true
+			addrs = append(addrs, addr)
+			p.found[peer.RunID] = addr
+		}
+	}
+	if len(drop) > 0 {
+		kept := addrs[:0]
+		for _, addr := range addrs {
+			if !drop[addr] {
+				kept = append(kept, addr)
+			}
+		}
+		addrs = kept
+	}
+	sc.replaceAddrs(addrs)
+}
+
+// peerAddrs returns addresses of the discovered sentinels. Client must be
+// locked.
+func (sc *Client) peerAddrs() []string {
+	if sc.peers == nil {
+		return nil
+	}
+	addrs := make([]string, 0, len(sc.peers.found))
+	for _, addr := range sc.peers.found {
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+// down reports whether the sentinel is flagged as down.
+func (s SentinelInfo) down() bool {
+	return flaggedDown(s.Flags)
+}
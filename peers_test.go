@@ -0,0 +1,56 @@
+package sentinel
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sentinelEntry is SENTINEL sentinels reply entry of the peer at addr.
+func sentinelEntry(addr, runID, flags, lastOK string) string {
+	host, port, _ := net.SplitHostPort(addr)
+	return bulkArr("name", runID, "ip", host, "port", port, "runid", runID,
+		"flags", flags, "last-ok-ping-reply", lastOK)
+}
+
+func TestSentinelDiscovery(t *testing.T) {
+	peer := newFake(t, basicHandler)
+	var mu sync.Mutex
+	entry := sentinelEntry(peer.addr(), "b2", "sentinel", "100")
+	s := newFake(t, func(args []string) string {
+		if len(args) == 3 && strings.EqualFold(args[1], "sentinels") {
+			mu.Lock()
+			defer mu.Unlock()
+			return "*1\r\n" + entry
+		}
+		return basicHandler(args)
+	})
+	c := NewClientWithOptions([]string{s.addr()}, WithSentinelDiscovery("mymaster", 10*time.Millisecond, time.Second))
+	defer c.Close()
+
+	waitFor(t, "peer to be discovered", func() bool { return len(c.Addrs()) == 2 })
+	if got, want := c.Addrs(), []string{s.addr(), peer.addr()}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Addrs() = %q, want %q", got, want)
+	}
+
+	// Peer down for less than dropAfter is kept.
+	mu.Lock()
+	entry = sentinelEntry(peer.addr(), "b2", "s_down,sentinel", "500")
+	queried := s.calls("SENTINEL sentinels mymaster")
+	mu.Unlock()
+	waitFor(t, "peer to be queried again", func() bool { return s.calls("SENTINEL sentinels mymaster") > queried+1 })
+	if n := len(c.Addrs()); n != 2 {
+		t.Fatalf("peer down for 500ms dropped, Addrs() = %q", c.Addrs())
+	}
+
+	mu.Lock()
+	entry = sentinelEntry(peer.addr(), "b2", "s_down,sentinel", "5000")
+	mu.Unlock()
+	waitFor(t, "down peer to be dropped", func() bool { return len(c.Addrs()) == 1 })
+	if got, want := c.Addrs(), []string{s.addr()}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Addrs() = %q, want %q", got, want)
+	}
+}
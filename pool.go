@@ -0,0 +1,246 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// errStaleConn is returned by the WatchedPool borrow check for connections
+// dialed before the last master switch.
+var errStaleConn = errors.New("sentinel: connection dialed before master switch")
+
+// Pool is a redis.Pool owning the sentinel Client used to resolve addresses
+// of the servers it connects to, unless created with NewPoolWithClient.
+type Pool struct {
+	*redis.Pool
+
+	client *sharedClient
+	once   sync.Once
+}
+
+func newOwnedPool(pool *redis.Pool, client *sharedClient) *Pool {
+	atomic.AddInt32(&client.refs, 1)
+	return &Pool{Pool: pool, client: client}
+}
+
+// Close closes the pool and then its sentinel client, once it is not used by
+// any other pool. Client passed to NewPoolWithClient is left open.
+func (p *Pool) Close() error {
+	err := p.Pool.Close()
+	if p.client != nil {
+		p.once.Do(p.client.release)
+	}
+	return err
+}
+
+// sharedClient is a sentinel Client closed once all the pools using it are
+// closed.
+type sharedClient struct {
+	*Client
+	refs int32
+}
+
+func (c *sharedClient) release() {
+	if atomic.AddInt32(&c.refs, -1) == 0 {
+		c.Client.Close()
+	}
+}
+
+// WatchedPool is a master pool that listens for the +switch-master events of
+// the configured master. Once a switch is announced all idle connections to
+// the old master are closed, so the next Get dials the new master instead of
+// waiting for a borrow check to fail.
+type WatchedPool struct {
+	*redis.Pool
+
+	client *Client
+	gen    uint64
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewWatchedPool creates WatchedPool based on Config struct provided. Error is
+// returned if config is invalid or none of the sentinels can be subscribed
+// to. Close must be called to stop watching for master switches.
+func NewWatchedPool(conf Config) (*WatchedPool, error) {
+	return newWatchedPool(conf, nil)
+}
+
+// newWatchedPool creates WatchedPool calling onSwitch, if set, with the new
+// master address on every master switch.
+func newWatchedPool(conf Config, onSwitch func(Addr)) (*WatchedPool, error) {
+	if err := validateConfig(conf); err != nil {
+		return nil, err
+	}
+
+	client := newSentinelClient(conf)
+	p := &WatchedPool{
+		Pool:   newPool(conf, client),
+		client: client,
+		done:   make(chan struct{}),
+	}
+
+	dial := p.Pool.DialContext
+	p.Pool.DialContext = func(ctx context.Context) (redis.Conn, error) {
+		gen := atomic.LoadUint64(&p.gen)
+		c, err := dial(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &genConn{Conn: c, gen: gen}, nil
+	}
+	testOnBorrow := p.Pool.TestOnBorrow
+	p.Pool.TestOnBorrow = func(c redis.Conn, t time.Time) error {
+		gc, ok := c.(*genConn)
+		if !ok || gc.gen != atomic.LoadUint64(&p.gen) {
+			return errStaleConn
+		}
+		return testOnBorrow(gc.Conn, t)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	switches, err := client.WatchMaster(ctx, conf.Master)
+	if err != nil {
+		cancel()
+		client.Close()
+		return nil, err
+	}
+	p.cancel = cancel
+
+	go func() {
+		defer close(p.done)
+		for ev := range switches {
+			client.Invalidate(conf.Master)
+			if onSwitch != nil {
+				onSwitch(conf.translate(ev.New))
+			}
+			p.Drain()
+		}
+	}()
+	return p, nil
+}
+
+// Drain closes all idle connections of the pool. Connections currently in use
+// are discarded when borrowed again after being returned to the pool.
+func (p *WatchedPool) Drain() {
+	atomic.AddUint64(&p.gen, 1)
+	if p.Pool.IdleCount() == 0 {
+		return
+	}
+	// Pool closes every idle connection failing the borrow check until it
+	// finds a valid one or dials a new connection, which is returned to the
+	// pool right away.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if c, err := p.Pool.GetContext(ctx); err == nil {
+		c.Close()
+	}
+}
+
+// Close stops watching for master switches and closes the pool and its
+// sentinel client.
+func (p *WatchedPool) Close() error {
+	p.cancel()
+	<-p.done
+	err := p.Pool.Close()
+	p.client.Close()
+	return err
+}
+
+// genConn is a connection tagged with the master switch generation it was
+// dialed in.
+type genConn struct {
+	redis.Conn
+	gen uint64
+}
+
+func (c *genConn) DoWithTimeout(timeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	return redis.DoWithTimeout(c.Conn, timeout, cmd, args...)
+}
+
+func (c *genConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return redis.ReceiveWithTimeout(c.Conn, timeout)
+}
+
+func (c *genConn) DoContext(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	return redis.DoContext(c.Conn, ctx, cmd, args...)
+}
+
+func (c *genConn) ReceiveContext(ctx context.Context) (interface{}, error) {
+	return redis.ReceiveContext(c.Conn, ctx)
+}
+
+// AutoPool is a master pool that transparently follows master changes. It
+// owns the sentinel client and the underlying pool, watches for master
+// switches and redirects new connections to the current master. Use NewPool
+// for a low level pool to be wired together by the caller.
+type AutoPool struct {
+	pool *WatchedPool
+	name string
+
+	mu   sync.Mutex
+	addr Addr
+}
+
+// NewAutoPool creates AutoPool based on Config struct provided. Error is
+// returned if config is invalid or none of the sentinels can be reached.
+func NewAutoPool(conf Config) (*AutoPool, error) {
+	p := &AutoPool{name: conf.Master}
+	pool, err := newWatchedPool(conf, p.setMasterAddr)
+	if err != nil {
+		return nil, err
+	}
+	p.pool = pool
+
+	if addr, err := pool.client.MasterAddr(conf.Master); err == nil {
+		p.mu.Lock()
+		if p.addr == (Addr{}) {
+			p.addr = conf.translate(addr)
+		}
+		p.mu.Unlock()
+	}
+	return p, nil
+}
+
+// Get gets a connection to the current master. See redis.Pool Get.
+func (p *AutoPool) Get() redis.Conn {
+	return p.pool.Get()
+}
+
+// GetContext gets a connection to the current master using the provided
+// context. See redis.Pool GetContext.
+func (p *AutoPool) GetContext(ctx context.Context) (redis.Conn, error) {
+	return p.pool.GetContext(ctx)
+}
+
+// MasterAddr returns address of the current master as last announced by
+// sentinel, translated with Config AddressTranslator if set. Zero Addr is
+// returned if the master could not be resolved yet.
+func (p *AutoPool) MasterAddr() Addr {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.addr
+}
+
+// Stats returns statistics of the underlying pool.
+func (p *AutoPool) Stats() redis.PoolStats {
+	return p.pool.Stats()
+}
+
+// Close stops watching for master switches and releases all the resources of
+// the pool.
+func (p *AutoPool) Close() error {
+	return p.pool.Close()
+}
+
+func (p *AutoPool) setMasterAddr(addr Addr) {
+	p.mu.Lock()
+	p.addr = addr
+	p.mu.Unlock()
+}
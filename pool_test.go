@@ -0,0 +1,283 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// roleMaster answers as redis master.
+func roleMaster(args []string) string {
+	if strings.EqualFold(args[0], "ROLE") {
+		return masterRole
+	}
+	return "+PONG\r\n"
+}
+
+func TestWatchedPoolDrainsOnSwitch(t *testing.T) {
+	oldMaster, newMaster := newFake(t, roleMaster), newFake(t, roleMaster)
+	failover := make(chan struct{})
+	var once sync.Once
+	announce := func() { once.Do(func() { close(failover) }) }
+	defer announce()
+
+	var mu sync.Mutex
+	current := pointTo(oldMaster.addr())
+	s := newFake(t, func(args []string) string {
+		if strings.EqualFold(args[0], "PSUBSCRIBE") {
+			// Subscription is confirmed once the failover is announced.
+			<-failover
+			return subscribed + switchMessage("mymaster", 6379, 6380)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return current(args)
+	})
+
+	p, err := NewWatchedPool(testConfig(s.addr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	c := p.Get()
+	if _, err := c.Do("PING"); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if n := oldMaster.openConns(); n != 1 {
+		t.Fatalf("%d connections open to the old master, want 1", n)
+	}
+
+	mu.Lock()
+	current = pointTo(newMaster.addr())
+	mu.Unlock()
+	announce()
+
+	waitFor(t, "idle connections to the old master closed", func() bool {
+		return oldMaster.openConns() == 0
+	})
+
+	c = p.Get()
+	defer c.Close()
+	if _, err := c.Do("PING"); err != nil {
+		t.Fatal(err)
+	}
+	if n := oldMaster.dialed(); n != 1 {
+		t.Errorf("old master dialed %d times, want 1", n)
+	}
+	if n := newMaster.calls("PING"); n != 1 {
+		t.Errorf("new master received %d PINGs, want 1", n)
+	}
+}
+
+// closer is a pool that can be closed.
+type closer interface {
+	Get() redis.Conn
+	Close() error
+}
+
+func TestPoolCloseReleasesSentinel(t *testing.T) {
+	master := newFake(t, roleMaster)
+	replica := newFake(t, func(args []string) string {
+		if strings.EqualFold(args[0], "ROLE") {
+			return replicaRole
+		}
+		return "+PONG\r\n"
+	})
+	host, port, _ := net.SplitHostPort(replica.addr())
+	sentinel := pointTo(master.addr())
+	handler := func(args []string) string {
+		switch {
+		case strings.EqualFold(args[0], "PSUBSCRIBE"):
+			return subscribed
+		case len(args) > 1 && strings.EqualFold(args[1], "replicas"):
+			return "*1\r\n" + replicaEntry(host, port, "slave")
+		}
+		return sentinel(args)
+	}
+
+	tests := []struct {
+		name string
+		open func(Config) (closer, error)
+	}{
+		{"NewPool", func(conf Config) (closer, error) { return NewPool(conf) }},
+		{"NewReplicaPool", func(conf Config) (closer, error) { return NewReplicaPool(conf) }},
+		{"NewWatchedPool", func(conf Config) (closer, error) { return NewWatchedPool(conf) }},
+		{"NewAutoPool", func(conf Config) (closer, error) { return NewAutoPool(conf) }},
+	}
+	for _, tt := range tests {
+		s := newFake(t, handler)
+		p, err := tt.open(testConfig(s.addr()))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		c := p.Get()
+		if _, err := c.Do("PING"); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		c.Close()
+		if s.openConns() == 0 {
+			t.Fatalf("%s: no sentinel connections open", tt.name)
+		}
+
+		p.Close()
+		waitFor(t, tt.name+" sentinel connections closed", func() bool {
+			return s.openConns() == 0
+		})
+	}
+}
+
+func TestSharedClientClose(t *testing.T) {
+	s, _ := newFakeMaster(t)
+	pools, err := NewPools(testConfig(s.addr()), "mymaster", "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := pools["mymaster"].Get()
+	if _, err := c.Do("PING"); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	pools["mymaster"].Close()
+	pools["mymaster"].Close()
+	if n := s.openConns(); n != 1 {
+		t.Fatalf("%d sentinel connections open while other pool is in use, want 1", n)
+	}
+	pools["other"].Close()
+	waitFor(t, "sentinel connections closed", func() bool {
+		return s.openConns() == 0
+	})
+}
+
+func TestPoolWithClientLeavesClientOpen(t *testing.T) {
+	s, _ := newFakeMaster(t)
+	client := NewClient([]string{s.addr()})
+	defer client.Close()
+	p, err := NewPoolWithClient(client, "mymaster", testConfig(s.addr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := p.Get()
+	if _, err := c.Do("PING"); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	p.Close()
+	if _, err := client.MasterAddress("mymaster"); err != nil {
+		t.Fatalf("client closed with the pool: %v", err)
+	}
+	if n := s.dialed(); n != 1 {
+		t.Errorf("sentinel dialed %d times, want idle connection kept", n)
+	}
+}
+
+func TestPoolWaitBlocksAtMaxActive(t *testing.T) {
+	s, _ := newFakeMaster(t)
+	conf := testConfig(s.addr())
+	conf.Pool.MaxActive, conf.Pool.Wait = 1, true
+	p, err := NewPool(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	held := p.Get()
+	defer held.Close()
+	if _, err := held.Do("PING"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	c, err := p.GetContext(ctx)
+	if err == nil {
+		c.Close()
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetContext() error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestReplicaPoolGetContext(t *testing.T) {
+	s := silentFake(t)
+	p, err := NewReplicaPool(testConfig(s.addr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer time.AfterFunc(20*time.Millisecond, cancel).Stop()
+	start := time.Now()
+	c, err := p.GetContext(ctx)
+	if err == nil {
+		c.Close()
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetContext() error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("GetContext() took %v, want replica lookup cancelled with ctx", elapsed)
+	}
+}
+
+func TestValidateNewPools(t *testing.T) {
+	conf := testConfig("127.0.0.1:26379")
+	tests := []struct {
+		name    string
+		masters []string
+		field   string
+		want    error
+	}{
+		{"no masters", nil, "masters", ErrNoMasters},
+		{"empty name", []string{"mymaster", ""}, "masters[1]", ErrEmptyMasterName},
+	}
+	for _, tt := range tests {
+		_, err := NewPools(conf, tt.masters...)
+		var cerr *ConfigError
+		if !errors.Is(err, tt.want) || !errors.As(err, &cerr) || cerr.Field != tt.field {
+			t.Errorf("%s: NewPools() error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	// Master in conf is ignored, sentinel settings are still checked.
+	conf.Master = ""
+	conf.SentinelTimeouts.Read = 0
+	_, err := NewPools(conf, "mymaster")
+	var cerr *ConfigError
+	if !errors.Is(err, ErrTimeoutNotSet) || !errors.As(err, &cerr) || cerr.Field != "SentinelTimeouts.Read" {
+		t.Errorf("NewPools() error = %v, want only SentinelTimeouts.Read not set", err)
+	}
+	if errors.Is(err, ErrMasterNotSet) {
+		t.Errorf("NewPools() error = %v, want conf.Master ignored", err)
+	}
+}
+
+func TestReplicaPoolSkipsBrokenLink(t *testing.T) {
+	s := newFake(t, func(args []string) string {
+		if len(args) > 1 && strings.EqualFold(args[1], "replicas") {
+			return "*1\r\n" + bulkArr("ip", "10.0.0.2", "port", "6380", "flags", "slave", "master-link-status", "err")
+		}
+		return basicHandler(args)
+	})
+	p, err := NewReplicaPool(testConfig(s.addr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	c := p.Get()
+	defer c.Close()
+	if _, err := c.Do("PING"); !errors.Is(err, ErrNoReplicas) {
+		t.Fatalf("Do() error = %v, want ErrNoReplicas", err)
+	}
+}
@@ -0,0 +1,266 @@
+package sentinel
+
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// PoolOption configures pool created with NewPoolWithOptions. Options modify
+// the pool Config and are applied in order, so later options override the
+// earlier ones.
+type PoolOption func(*Config)
+
+// NewPoolWithOptions creates redigo/redis.Pool instance connecting to the
+// named master monitored by the sentinels, configured with the provided
+// options. Options are applied on top of Config with the recommended
+// DefaultSentinelTimeout and DefaultRedisTimeout timeouts and DefaultKeepAlive
+// of redis connections set, all the other fields are zero, so the pool
+// behaves like NewPool given the same Config.
+// Use WithConfig(DefaultConfig(master, sentinels...)) to start with
+// DefaultConfig instead. Error is returned if the resulting configuration is
+// invalid, see NewPool.
+func NewPoolWithOptions(master string, sentinels []string, opts ...PoolOption) (*Pool, error) {
+	conf := timeoutsConfig(master, sentinels...)
+	for _, opt := range opts {
+		opt(&conf)
+	}
+	conf.Master = master
+	conf.Sentinels = sentinels
+	if err := validateConfig(conf); err != nil {
+		return nil, err
+	}
+
+	client := newSentinelClient(conf)
+	return newOwnedPool(newPool(conf, client), &sharedClient{Client: client}), nil
+}
+
+// NewPools creates pools connecting to each of the named masters monitored by
+// the sentinels in Config, sharing a single sentinel Client. Master in conf
+// is ignored. Either all the pools are created or error is returned if the
+// configuration is invalid, errors matching ErrNoMasters and
+// ErrEmptyMasterName are returned if no or empty master names are given. The
+// sentinel client is closed once all of the pools are closed.
+func NewPools(conf Config, masters ...string) (map[string]*Pool, error) {
+	if err := validatePoolsConfig(conf, masters); err != nil {
+		return nil, err
+	}
+
+	// Sentinel peers are discovered through the first master.
+	conf.Master = masters[0]
+	client := &sharedClient{Client: newSentinelClient(conf)}
+	pools := make(map[string]*Pool, len(masters))
+	for _, name := range masters {
+		if _, ok := pools[name]; ok {
+			continue
+		}
+		conf.Master = name
+		pools[name] = newOwnedPool(newPool(conf, client.Client), client)
+	}
+	return pools, nil
+}
+
+// NewPoolWithClient creates redigo/redis.Pool instance connecting to the named
+// master using an existing sentinel Client, so pools of several masters can
+// share its connections, caches and sentinel health state. Sentinel
+// configuration in conf is ignored as the Client is already set up. The
+// client is not closed by the pool and must outlive it.
+func NewPoolWithClient(client *Client, master string, conf Config) (*Pool, error) {
+	conf.Master = master
+	if err := validatePoolConfig(conf); err != nil {
+		return nil, err
+	}
+
+	return &Pool{Pool: newPool(conf, client)}, nil
+}
+
+// WithConfig replaces the whole pool configuration with conf. Master and
+// sentinel addresses passed to NewPoolWithOptions take precedence over the
+// ones in conf.
+func WithConfig(conf Config) PoolOption {
+	return func(c *Config) {
+		*c = conf
+	}
+}
+
+// WithSentinelTimeouts sets connect, read and write timeouts of sentinel
+// connections.
+func WithSentinelTimeouts(connect, read, write time.Duration) PoolOption {
+	return func(c *Config) {
+		c.SentinelTimeouts.Connect = connect
+		c.SentinelTimeouts.Read = read
+		c.SentinelTimeouts.Write = write
+	}
+}
+
+// WithSentinelTotalTimeout sets the deadline of a single lookup spanning all
+// the attempts across sentinels.
+func WithSentinelTotalTimeout(total time.Duration) PoolOption {
+	return func(c *Config) {
+		c.SentinelTimeouts.Total = total
+	}
+}
+
+// WithRedisTimeouts sets connect, read and write timeouts of redis
+// connections handed out by the pool.
+func WithRedisTimeouts(connect, read, write time.Duration) PoolOption {
+	return func(c *Config) {
+		c.RedisTimeouts.Connect = connect
+		c.RedisTimeouts.Read = read
+		c.RedisTimeouts.Write = write
+	}
+}
+
+// WithSentinelAuth sets credentials used to authenticate to the sentinels.
+func WithSentinelAuth(username, password string) PoolOption {
+	return func(c *Config) {
+		c.SentinelUsername = username
+		c.SentinelPassword = password
+	}
+}
+
+// WithRedisAuth sets credentials used to authenticate redis connections.
+func WithRedisAuth(username, password string) PoolOption {
+	return func(c *Config) {
+		c.RedisUsername = username
+		c.RedisPassword = password
+	}
+}
+
+// WithSentinelTLS enables TLS for sentinel connections.
+func WithSentinelTLS(conf *tls.Config) PoolOption {
+	return func(c *Config) {
+		c.SentinelTLS = conf
+	}
+}
+
+// WithRedisTLS enables TLS for redis connections. Nil conf uses the default
+// TLS configuration.
+func WithRedisTLS(conf *tls.Config) PoolOption {
+	return func(c *Config) {
+		c.RedisUseTLS = true
+		c.RedisTLS = conf
+	}
+}
+
+// WithRedisDB selects the database on redis connections.
+func WithRedisDB(db int) PoolOption {
+	return func(c *Config) {
+		c.RedisDB = db
+	}
+}
+
+// WithAddressTranslator sets function rewriting master and replica addresses
+// announced by sentinel, see Config AddressTranslator.
+func WithAddressTranslator(fn func(Addr) Addr) PoolOption {
+	return func(c *Config) {
+		c.AddressTranslator = fn
+	}
+}
+
+// WithDialers sets functions dialing sentinel and redis connections instead
+// of the default net.Dialer. Nil keeps the default.
+func WithDialers(sentinel, redis DialFunc) PoolOption {
+	return func(c *Config) {
+		c.SentinelDialer = sentinel
+		c.RedisDialer = redis
+	}
+}
+
+// WithRedisDialOptions adds options for dialing redis connections.
+func WithRedisDialOptions(options ...redis.DialOption) PoolOption {
+	return func(c *Config) {
+		c.RedisDialOptions = append(c.RedisDialOptions[:len(c.RedisDialOptions):len(c.RedisDialOptions)], options...)
+	}
+}
+
+// WithOnConnect sets function called for every newly dialed connection, see
+// Config OnConnect.
+func WithOnConnect(fn func(redis.Conn) error) PoolOption {
+	return func(c *Config) {
+		c.OnConnect = fn
+	}
+}
+
+// WithMasterCacheTTL enables caching of the resolved master address.
+func WithMasterCacheTTL(ttl time.Duration) PoolOption {
+	return func(c *Config) {
+		c.MasterCacheTTL = ttl
+	}
+}
+
+// WithMaxIdle sets the maximum number of idle connections kept by the pool.
+func WithMaxIdle(n int) PoolOption {
+	return func(c *Config) {
+		c.Pool.MaxIdle = n
+	}
+}
+
+// WithMaxActive limits the number of connections allocated by the pool. If
+// wait is set Get waits for a connection once the limit is reached.
+func WithMaxActive(n int, wait bool) PoolOption {
+	return func(c *Config) {
+		c.Pool.MaxActive = n
+		c.Pool.Wait = wait
+	}
+}
+
+// WithIdleTimeout closes connections idle for longer than the timeout.
+func WithIdleTimeout(timeout time.Duration) PoolOption {
+	return func(c *Config) {
+		c.Pool.IdleTimeout = timeout
+	}
+}
+
+// WithMaxConnLifetime closes connections older than the duration.
+func WithMaxConnLifetime(d time.Duration) PoolOption {
+	return func(c *Config) {
+		c.Pool.MaxConnLifetime = d
+	}
+}
+
+// WithConnChecks selects checks of borrowed and newly dialed connections, see
+// ConnCheck.
+func WithConnChecks(borrow, dial ConnCheck) PoolOption {
+	return func(c *Config) {
+		c.Pool.BorrowCheck = borrow
+		c.Pool.DialCheck = dial
+	}
+}
+
+// WithLoadingCheck makes the pool Dial fail with ErrMasterLoading while the
+// master is loading its dataset, see Config Pool.CheckLoading.
+func WithLoadingCheck() PoolOption {
+	return func(c *Config) {
+		c.Pool.CheckLoading = true
+	}
+}
+
+// WithWritableCheck makes the pool Dial fail with ErrMasterReadOnly if the
+// master refuses writes. Key is written to probe the master if not empty,
+// see Config Pool.CheckWritable.
+func WithWritableCheck(probeKey string) PoolOption {
+	return func(c *Config) {
+		c.Pool.CheckWritable = true
+		c.Pool.WriteProbeKey = probeKey
+	}
+}
+
+// WithMinReplicas makes the pool Dial fail with ErrInsufficientReplicas if
+// master has fewer than n replicas online and lagging at most maxLag behind,
+// see Config MinReplicas.
+func WithMinReplicas(n int, maxLag time.Duration) PoolOption {
+	return func(c *Config) {
+		c.MinReplicas = n
+		c.MaxReplicaLag = maxLag
+	}
+}
+
+// WithTestOnBorrowAfter skips the check of connections used less than
+// the duration ago. Zero checks every borrowed connection.
+func WithTestOnBorrowAfter(d time.Duration) PoolOption {
+	return func(c *Config) {
+		c.Pool.TestOnBorrowAfter = d
+	}
+}
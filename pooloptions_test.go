@@ -0,0 +1,111 @@
+package sentinel
+
+import (
+	"context"
+	"crypto/tls"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPoolOptions(t *testing.T) {
+	tlsConf := &tls.Config{ServerName: "redis"}
+	tests := []struct {
+		name string
+		opt  PoolOption
+		want func(*Config)
+	}{
+		{"SentinelTimeouts", WithSentinelTimeouts(1, 2, 3), func(c *Config) {
+			c.SentinelTimeouts.Connect, c.SentinelTimeouts.Read, c.SentinelTimeouts.Write = 1, 2, 3
+		}},
+		{"SentinelTotalTimeout", WithSentinelTotalTimeout(time.Second), func(c *Config) { c.SentinelTimeouts.Total = time.Second }},
+		{"RedisTimeouts", WithRedisTimeouts(1, 2, 3), func(c *Config) {
+			c.RedisTimeouts.Connect, c.RedisTimeouts.Read, c.RedisTimeouts.Write = 1, 2, 3
+		}},
+		{"SentinelAuth", WithSentinelAuth("u", "p"), func(c *Config) { c.SentinelUsername, c.SentinelPassword = "u", "p" }},
+		{"RedisAuth", WithRedisAuth("u", "p"), func(c *Config) { c.RedisUsername, c.RedisPassword = "u", "p" }},
+		{"SentinelTLS", WithSentinelTLS(tlsConf), func(c *Config) { c.SentinelTLS = tlsConf }},
+		{"RedisTLS", WithRedisTLS(nil), func(c *Config) { c.RedisUseTLS = true }},
+		{"RedisDB", WithRedisDB(3), func(c *Config) { c.RedisDB = 3 }},
+		{"MasterCacheTTL", WithMasterCacheTTL(time.Second), func(c *Config) { c.MasterCacheTTL = time.Second }},
+		{"MaxIdle", WithMaxIdle(4), func(c *Config) { c.Pool.MaxIdle = 4 }},
+		{"MaxActive", WithMaxActive(5, true), func(c *Config) { c.Pool.MaxActive, c.Pool.Wait = 5, true }},
+		{"IdleTimeout", WithIdleTimeout(time.Minute), func(c *Config) { c.Pool.IdleTimeout = time.Minute }},
+		{"MaxConnLifetime", WithMaxConnLifetime(time.Hour), func(c *Config) { c.Pool.MaxConnLifetime = time.Hour }},
+		{"ConnChecks", WithConnChecks(CheckPing, CheckNone), func(c *Config) { c.Pool.BorrowCheck, c.Pool.DialCheck = CheckPing, CheckNone }},
+		{"LoadingCheck", WithLoadingCheck(), func(c *Config) { c.Pool.CheckLoading = true }},
+		{"WritableCheck", WithWritableCheck("probe"), func(c *Config) { c.Pool.CheckWritable, c.Pool.WriteProbeKey = true, "probe" }},
+		{"MinReplicas", WithMinReplicas(2, time.Second), func(c *Config) { c.MinReplicas, c.MaxReplicaLag = 2, time.Second }},
+		{"TestOnBorrowAfter", WithTestOnBorrowAfter(time.Second), func(c *Config) { c.Pool.TestOnBorrowAfter = time.Second }},
+		{"Config", WithConfig(DefaultConfig("other")), func(c *Config) { *c = DefaultConfig("other") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := timeoutsConfig("mymaster", "127.0.0.1:26379")
+			tt.opt(&got)
+			want := timeoutsConfig("mymaster", "127.0.0.1:26379")
+			tt.want(&want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("option set %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestNewPoolWithOptionsDefaults(t *testing.T) {
+	conf := timeoutsConfig("mymaster", "127.0.0.1:26379")
+	if conf.SentinelKeepAlive != 0 || conf.Pool.TestOnBorrowAfter != 0 {
+		t.Fatalf("defaults differ from NewPool zero values: %+v", conf)
+	}
+	if conf.RedisKeepAlive != DefaultKeepAlive {
+		t.Fatalf("RedisKeepAlive = %v, want %v", conf.RedisKeepAlive, DefaultKeepAlive)
+	}
+	if err := validateConfig(conf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewPoolWithOptions("mymaster", []string{"127.0.0.1:26379"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewPoolWithOptionsDial(t *testing.T) {
+	s, cmds := newFakeMaster(t)
+	p, err := NewPoolWithOptions("mymaster", []string{s.addr()}, WithRedisDB(3), WithRedisAuth("", "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	c, err := p.DialContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	got := cmds()
+	if len(got) < 2 || got[0] != "AUTH secret" || got[1] != "SELECT 3" {
+		t.Fatalf("master received %q", got)
+	}
+}
+
+func TestNewPoolWithClient(t *testing.T) {
+	s, _ := newFakeMaster(t)
+	client := NewClient([]string{s.addr()})
+	defer client.Close()
+
+	p, err := NewPoolWithClient(client, "mymaster", testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := p.DialContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	p.Close()
+
+	client.Lock()
+	closed := client.closed
+	client.Unlock()
+	if closed {
+		t.Fatal("client closed with the pool")
+	}
+}
@@ -0,0 +1,100 @@
+package sentinel
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SentinelAnswer is a master address reported by a single sentinel.
+type SentinelAnswer struct {
+	Sentinel string
+	Addr     Addr
+	// Err is set if the sentinel failed to answer.
+	Err error
+}
+
+// DisagreementError is returned by master address lookups in the quorum
+// agreement mode when the majority of sentinels does not agree on the master
+// address.
+type DisagreementError struct {
+	Name    string
+	Answers []SentinelAnswer
+}
+
+func (e *DisagreementError) Error() string {
+	answers := make([]string, 0, len(e.Answers))
+	for _, a := range e.Answers {
+		if a.Err != nil {
+			answers = append(answers, fmt.Sprintf("%s: %s", a.Sentinel, a.Err))
+		} else {
+			answers = append(answers, fmt.Sprintf("%s: %s", a.Sentinel, a.Addr))
+		}
+	}
+	return fmt.Sprintf("sentinel: no majority agreement on master %s address: %s", e.Name, strings.Join(answers, "; "))
+}
+
+// masterAddrQuorum queries all the sentinels concurrently and returns the
+// master address only if the majority of the configured sentinels agree on
+// it. Remaining queries are cancelled as soon as the outcome is known.
+func (sc *Client) masterAddrQuorum(parent context.Context, name string) (Addr, error) {
+	addrs := sc.sentinelsFromActive()
+	if len(addrs) == 0 {
+		return Addr{}, ErrNoSentinelsConfigured
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	if sc.totalTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, sc.totalTimeout)
+		defer cancel()
+	}
+
+	answers := make(chan SentinelAnswer, len(addrs))
+	for _, addr := range addrs {
+		go func(addr string) {
+			answer := SentinelAnswer{Sentinel: addr}
+			reply, tilt, err := sc.doOnce(ctx, addr, true, "SENTINEL", "get-master-addr-by-name", name)
+			if err == nil && tilt {
+				err = errTilt
+			}
+			if err == nil {
+				answer.Addr, err = parseMasterAddrReply(name, addr, reply)
+			}
+			answer.Err = err
+			answers <- answer
+		}(addr)
+	}
+
+	majority := len(addrs)/2 + 1
+	votes := make(map[Addr]int)
+	collected := make([]SentinelAnswer, 0, len(addrs))
+	for len(collected) < len(addrs) {
+		var a SentinelAnswer
+		select {
+		case a = <-answers:
+		case <-ctx.Done():
+			return Addr{}, sc.contextError(parent, len(addrs))
+		}
+		collected = append(collected, a)
+		if a.Err == nil {
+			votes[a.Addr]++
+			if votes[a.Addr] >= majority {
+				return a.Addr, nil
+			}
+		}
+
+		best := 0
+		for _, n := range votes {
+			if n > best {
+				best = n
+			}
+		}
+		if best+len(addrs)-len(collected) < majority {
+			// Majority can not be reached anymore.
+			break
+		}
+	}
+
+	return Addr{}, &DisagreementError{Name: name, Answers: collected}
+}
@@ -0,0 +1,149 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrLookupRateLimited is returned by master address lookups rejected by the
+// rate limiter before any lookup result could be shared, see
+// WithLookupRateLimit.
+var ErrLookupRateLimited = errors.New("sentinel: master lookup rate limited")
+
+// RateLimitMode selects what happens to master address lookups exceeding the
+// rate limit.
+type RateLimitMode int
+
+const (
+	// RateLimitShareResult makes excess lookups return the result of the
+	// last lookup that reached sentinels, typically the last error during an
+	// outage.
+	RateLimitShareResult RateLimitMode = iota
+	// RateLimitWait makes excess lookups wait until they are allowed to query
+	// sentinels.
+	RateLimitWait
+)
+
+// RateLimitStats are the counters of master address lookups of a single
+// master limited by the rate limiter.
+type RateLimitStats struct {
+	// Dropped is the number of lookups that got the last lookup result
+	// instead of querying sentinels.
+	Dropped uint64
+	// Waited is the number of lookups that had to wait before querying
+	// sentinels.
+	Waited uint64
+}
+
+// lookupLimiter is a token bucket rate limiter of master address lookups,
+// with a separate bucket for every master name.
+type lookupLimiter struct {
+	rate  float64
+	burst int
+	mode  RateLimitMode
+
+	mu      sync.Mutex
+	buckets map[string]*lookupBucket
+}
+
+type lookupBucket struct {
+	tokens float64
+	last   time.Time
+
+	// result of the last lookup that reached sentinels.
+	done bool
+	addr Addr
+	err  error
+
+	stats RateLimitStats
+}
+
+// bucket returns the bucket of the named master refilled up to now, creating
+// it if necessary. Must be called with l.mu held.
+func (l *lookupLimiter) bucket(name string) *lookupBucket {
+	if l.buckets == nil {
+		l.buckets = make(map[string]*lookupBucket)
+	}
+	now := time.Now()
+	b, ok := l.buckets[name]
+	if !ok {
+		b = &lookupBucket{tokens: float64(l.burst), last: now}
+		l.buckets[name] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > float64(l.burst) {
+		b.tokens = float64(l.burst)
+	}
+	b.last = now
+	return b
+}
+
+// allow reports if a lookup of the named master may query sentinels. If it
+// may not, the shared result is returned instead. In the RateLimitWait mode
+// it waits for its turn until ctx is done.
+func (l *lookupLimiter) allow(ctx context.Context, name string) (ok bool, addr Addr, err error) {
+	l.mu.Lock()
+	b := l.bucket(name)
+	if b.tokens >= 1 {
+		b.tokens--
+		l.mu.Unlock()
+		return true, Addr{}, nil
+	}
+
+	if l.mode != RateLimitWait {
+		b.stats.Dropped++
+		addr, err = b.addr, b.err
+		if !b.done {
+			err = ErrLookupRateLimited
+		}
+		l.mu.Unlock()
+		return false, addr, err
+	}
+
+	// Reserve the token and wait until it is refilled.
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	b.tokens--
+	b.stats.Waited++
+	l.mu.Unlock()
+
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true, Addr{}, nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.bucket(name).tokens++
+		l.mu.Unlock()
+		return false, Addr{}, wrapError("sentinel", ctx.Err())
+	}
+}
+
+// record saves result of the named master lookup to be shared with excess
+// lookups.
+func (l *lookupLimiter) record(name string, addr Addr, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(name)
+	b.done = true
+	b.addr, b.err = addr, err
+}
+
+// RateLimitStats returns the rate limiter counters by master name. Nil is
+// returned if rate limiting is not enabled, see WithLookupRateLimit.
+func (sc *Client) RateLimitStats() map[string]RateLimitStats {
+	if sc.limiter == nil {
+		return nil
+	}
+	sc.limiter.mu.Lock()
+	defer sc.limiter.mu.Unlock()
+
+	stats := make(map[string]RateLimitStats, len(sc.limiter.buckets))
+	for name, b := range sc.limiter.buckets {
+		stats[name] = b.stats
+	}
+	return stats
+}
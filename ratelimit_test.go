@@ -0,0 +1,71 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLookupRateLimitShareResult(t *testing.T) {
+	s := newFake(t, basicHandler)
+	c := NewClientWithOptions([]string{s.addr()}, WithLookupRateLimit(0.001, 2, RateLimitShareResult))
+	defer c.Close()
+
+	for i := 0; i < 3; i++ {
+		addr, err := c.MasterAddress("mymaster")
+		if err != nil || addr != "[fd00::1]:6379" {
+			t.Fatalf("lookup %d: MasterAddress() = %q, %v", i, addr, err)
+		}
+	}
+	if n := s.calls(getMaster); n != 2 {
+		t.Errorf("sentinel queried %d times, want burst of 2", n)
+	}
+
+	// Every master name has its own limit.
+	if _, err := c.MasterAddress("other"); !errors.Is(err, ErrMasterUnknown) {
+		t.Errorf("MasterAddress(other) error = %v, want ErrMasterUnknown", err)
+	}
+	if n := s.calls("SENTINEL get-master-addr-by-name other"); n != 1 {
+		t.Errorf("other master queried %d times, want 1", n)
+	}
+
+	stats := c.RateLimitStats()
+	if want := (RateLimitStats{Dropped: 1}); stats["mymaster"] != want {
+		t.Errorf("RateLimitStats() = %+v, want mymaster %+v", stats, want)
+	}
+}
+
+func TestLookupRateLimitWait(t *testing.T) {
+	s := newFake(t, basicHandler)
+	c := NewClientWithOptions([]string{s.addr()}, WithLookupRateLimit(20, 1, RateLimitWait))
+	defer c.Close()
+
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if _, err := c.MasterAddress("mymaster"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("lookups took %v, want the second one to wait for 50ms", elapsed)
+	}
+	if n := s.calls(getMaster); n != 2 {
+		t.Errorf("sentinel queried %d times, want 2", n)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if _, err := c.MasterAddressContext(ctx, "mymaster"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("MasterAddressContext() error = %v, want context.DeadlineExceeded", err)
+	}
+	if got := c.RateLimitStats()["mymaster"]; got.Waited != 2 {
+		t.Errorf("RateLimitStats() = %+v, want 2 waits", got)
+	}
+
+	unlimited := NewClient([]string{s.addr()})
+	defer unlimited.Close()
+	if stats := unlimited.RateLimitStats(); stats != nil {
+		t.Errorf("RateLimitStats() = %v without rate limiting, want nil", stats)
+	}
+}
@@ -0,0 +1,81 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// ErrInsufficientReplicas is returned by the pool Dial function if master has
+// fewer connected replicas than required by Config MinReplicas.
+var ErrInsufficientReplicas = errors.New("sentinel: not enough replicas connected to master")
+
+type skipReplicaCheckKey struct{}
+
+// WithoutReplicaCheck returns a context making the pool Dial skip the check
+// of connected replicas required by Config MinReplicas, e.g. to reach the
+// master when replicas are known to be gone. Pass it to the pool GetContext.
+// Connections already idle in the pool are handed out without the check
+// regardless of the context.
+func WithoutReplicaCheck(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipReplicaCheckKey{}, true)
+}
+
+// checkReplicas returns ErrInsufficientReplicas if fewer than min replicas
+// are online and lagging at most maxLag behind the master at c. Zero maxLag
+// accepts any lag.
+func checkReplicas(ctx context.Context, c redis.Conn, min int, maxLag time.Duration) error {
+	if skip, _ := ctx.Value(skipReplicaCheckKey{}).(bool); skip {
+		return nil
+	}
+	res, err := redis.String(c.Do("INFO", "replication"))
+	if err != nil {
+		return err
+	}
+	info, err := parseServerInfo(res)
+	if err != nil {
+		return err
+	}
+	n := 0
+	for i := 0; ; i++ {
+		line, ok := info.Raw["slave"+strconv.Itoa(i)]
+		if !ok {
+			break
+		}
+		if replicaInSync(line, maxLag) {
+			n++
+		}
+	}
+	if n < min {
+		return fmt.Errorf("%w: %d of %d required", ErrInsufficientReplicas, n, min)
+	}
+	return nil
+}
+
+// replicaInSync parses the slaveN line of INFO replication, e.g.
+// "ip=10.0.0.2,port=6379,state=online,offset=1234,lag=0", and reports if the
+// replica is online and lags at most maxLag behind.
+func replicaInSync(line string, maxLag time.Duration) bool {
+	fields := make(map[string]string)
+	for _, kv := range strings.Split(line, ",") {
+		if i := strings.IndexByte(kv, '='); i >= 0 {
+			fields[kv[:i]] = kv[i+1:]
+		}
+	}
+	if fields["state"] != "online" {
+		return false
+	}
+	if maxLag == 0 {
+		return true
+	}
+	lag, err := strconv.Atoi(fields["lag"])
+	if err != nil {
+		return false
+	}
+	return time.Duration(lag)*time.Second <= maxLag
+}
@@ -0,0 +1,129 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// replicationInfo formats INFO replication section of master with the
+// replica lines given.
+func replicationInfo(replicas ...string) string {
+	s := "# Replication\r\nrole:master\r\nconnected_slaves:" + strconv.Itoa(len(replicas)) + "\r\n"
+	for i, r := range replicas {
+		s += "slave" + strconv.Itoa(i) + ":" + r + "\r\n"
+	}
+	return s + "master_repl_offset:10\r\n"
+}
+
+// infoMaster starts a fake master answering INFO replication with the
+// payload returned by info.
+func infoMaster(t *testing.T, info func() string) *fakeSentinel {
+	return newFake(t, func(args []string) string {
+		switch strings.ToUpper(args[0]) {
+		case "ROLE":
+			return masterRole
+		case "INFO":
+			return bulk(info())
+		}
+		return "+OK\r\n"
+	})
+}
+
+func TestMinReplicas(t *testing.T) {
+	var mu sync.Mutex
+	var payload string
+	master := infoMaster(t, func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return payload
+	})
+	s := newFake(t, pointTo(master.addr()))
+	p, err := NewPoolWithOptions("mymaster", []string{s.addr()},
+		WithConfig(testConfig(s.addr())), WithMinReplicas(2, 5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	tests := []struct {
+		name string
+		info string
+		ok   bool
+	}{
+		{"no replicas", replicationInfo(), false},
+		{"in sync", replicationInfo(
+			"ip=10.0.0.2,port=6379,state=online,offset=10,lag=0",
+			"ip=10.0.0.3,port=6379,state=online,offset=10,lag=5"), true},
+		{"lagging", replicationInfo(
+			"ip=10.0.0.2,port=6379,state=online,offset=10,lag=0",
+			"ip=10.0.0.3,port=6379,state=online,offset=4,lag=6"), false},
+		{"syncing", replicationInfo(
+			"ip=10.0.0.2,port=6379,state=online,offset=10,lag=0",
+			"ip=10.0.0.3,port=6379,state=wait_bgsave,offset=0,lag=0"), false},
+		{"enough of three", replicationInfo(
+			"ip=10.0.0.2,port=6379,state=online,offset=10,lag=1",
+			"ip=10.0.0.3,port=6379,state=send_bulk,offset=0,lag=0",
+			"ip=10.0.0.4,port=6379,state=online,offset=9,lag=2"), true},
+		{"malformed lag", replicationInfo(
+			"ip=10.0.0.2,port=6379,state=online,offset=10,lag=0",
+			"ip=10.0.0.3,port=6379,state=online,offset=10,lag=x"), false},
+	}
+	for _, tt := range tests {
+		mu.Lock()
+		payload = tt.info
+		mu.Unlock()
+
+		c, err := p.DialContext(context.Background())
+		if err == nil {
+			c.Close()
+		}
+		if tt.ok && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrInsufficientReplicas) {
+			t.Errorf("%s: DialContext() error = %v, want ErrInsufficientReplicas", tt.name, err)
+		}
+	}
+}
+
+func TestWithoutReplicaCheck(t *testing.T) {
+	master := infoMaster(t, func() string { return replicationInfo() })
+	s := newFake(t, pointTo(master.addr()))
+	conf := testConfig(s.addr())
+	conf.MinReplicas = 1
+	p, err := NewPool(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	if _, err := p.GetContext(context.Background()); !errors.Is(err, ErrInsufficientReplicas) {
+		t.Fatalf("GetContext() error = %v, want ErrInsufficientReplicas", err)
+	}
+	infos := master.calls("INFO replication")
+	c, err := p.GetContext(WithoutReplicaCheck(context.Background()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if n := master.calls("INFO replication"); n != infos {
+		t.Errorf("INFO replication sent %d times with check skipped", n-infos)
+	}
+}
+
+func TestValidateMinReplicas(t *testing.T) {
+	conf := testConfig("127.0.0.1:26379")
+	conf.MinReplicas = -1
+	if err := validateConfig(conf); !errors.Is(err, ErrNegativeValue) {
+		t.Errorf("MinReplicas: validateConfig() error = %v, want ErrNegativeValue", err)
+	}
+	conf.MinReplicas, conf.MaxReplicaLag = 1, -time.Second
+	if err := validateConfig(conf); !errors.Is(err, ErrNegativeValue) {
+		t.Errorf("MaxReplicaLag: validateConfig() error = %v, want ErrNegativeValue", err)
+	}
+}
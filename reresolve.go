@@ -0,0 +1,117 @@
+package sentinel
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// reresolver periodically resolves sentinel hostnames, see WithReresolve.
+type reresolver struct {
+	interval time.Duration
+	cancel   context.CancelFunc
+	// ips are the last resolved IP addresses by sentinel address, owned by
+	// the resolving goroutine.
+	ips    map[string]string
+	lookup func(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// startReresolve starts resolving sentinel hostnames if enabled by
+// WithReresolve.
+func (sc *Client) startReresolve() {
+	r := sc.reresolver
+	if r == nil {
+		return
+	}
+	if r.lookup == nil {
+		resolver := sc.resolver
+		if resolver == nil {
+			resolver = net.DefaultResolver
+		}
+		r.lookup = resolver.LookupIPAddr
+	}
+	r.ips = make(map[string]string)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	go func() {
+		t := time.NewTicker(r.interval)
+		defer t.Stop()
+		for {
+			r.resolve(ctx, sc)
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+		}
+	}()
+}
+
+// resolve resolves hostnames of all the sentinels and drops connections to
+// the sentinels with changed IP addresses.
+func (r *reresolver) resolve(ctx context.Context, sc *Client) {
+	sc.Lock()
+	addrs := append([]string(nil), sc.addrs...)
+	sc.Unlock()
+
+	for _, addr := range addrs {
+		host, ok := addrHostname(addr)
+		if !ok {
+			continue
+		}
+		lctx, cancel := context.WithTimeout(ctx, dnsLookupTimeout)
+		ips, err := r.lookup(lctx, host)
+		cancel()
+		if err != nil || len(ips) == 0 {
+			// Keep using the previous resolution.
+			continue
+		}
+		list := make([]string, len(ips))
+		for i, ip := range ips {
+			list[i] = ip.String()
+		}
+		sort.Strings(list)
+		resolved := strings.Join(list, ",")
+
+		prev, known := r.ips[addr]
+		r.ips[addr] = resolved
+		if known && prev != resolved {
+			sc.dropConns(addr)
+		}
+	}
+}
+
+// dropConns closes idle connections to the sentinel at addr and marks the
+// connections in use to be closed once returned.
+func (sc *Client) dropConns(addr string) {
+	sc.Lock()
+	defer sc.Unlock()
+
+	if sc.addrGen == nil {
+		sc.addrGen = make(map[string]uint64)
+	}
+	sc.addrGen[addr]++
+	for _, c := range sc.idle[addr] {
+		c.Close()
+	}
+	delete(sc.idle, addr)
+}
+
+// addrHostname returns hostname of the sentinel address. False is returned
+// for IP addresses and unix domain sockets.
+func addrHostname(addr string) (string, bool) {
+	scheme, hostport, ok := strings.Cut(addr, "://")
+	if !ok {
+		hostport = addr
+	} else if scheme == "unix" {
+		return "", false
+	}
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil || net.ParseIP(host) != nil {
+		return "", false
+	}
+	return host, true
+}
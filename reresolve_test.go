@@ -0,0 +1,50 @@
+package sentinel
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReresolve(t *testing.T) {
+	s := newFake(t, basicHandler)
+	_, port, _ := net.SplitHostPort(s.addr())
+	var mu sync.Mutex
+	ip := "10.0.0.1"
+	lookup := func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return []net.IPAddr{{IP: net.ParseIP(ip)}}, nil
+	}
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, strings.Replace(addr, "sentinel.test", "127.0.0.1", 1))
+	}
+	c := NewClientWithOptions([]string{net.JoinHostPort("sentinel.test", port)},
+		WithDialer(dial), WithReresolve(10*time.Millisecond), func(sc *Client) {
+			sc.reresolver.lookup = lookup
+		})
+	defer c.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.MasterAddress("mymaster"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := s.dialed(); n != 1 {
+		t.Fatalf("dialed %d times before IP change, want 1", n)
+	}
+
+	mu.Lock()
+	ip = "10.0.0.2"
+	mu.Unlock()
+	waitFor(t, "idle connection to be dropped", func() bool { return s.openConns() == 0 })
+	if _, err := c.MasterAddress("mymaster"); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.dialed(); n != 2 {
+		t.Errorf("dialed %d times after IP change, want 2", n)
+	}
+}
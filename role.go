@@ -0,0 +1,112 @@
+package sentinel
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Role is a parsed reply of the ROLE command.
+type Role struct {
+	// Role is one of "master", "slave" or "sentinel".
+	Role string
+	// Offset is the replication offset of master, or the offset processed
+	// by replica. It is -1 while replica is not connected to master.
+	Offset int64
+	// Replicas lists replicas connected to master.
+	Replicas []ReplicaOffset
+	// Master is the address of the master replica is attached to.
+	Master Addr
+	// LinkState is the state of replica link to master: "connect",
+	// "connecting", "sync" or "connected".
+	LinkState string
+	// Masters lists the names of masters monitored by sentinel.
+	Masters []string
+}
+
+// ReplicaOffset is a replica connected to master and its acknowledged
+// replication offset.
+type ReplicaOffset struct {
+	Addr   Addr
+	Offset int64
+}
+
+// RoleInfo returns the role of redis server together with the replication
+// state reported by the ROLE command. Unlike TestRole it does not fall back
+// to INFO if ROLE command is not available.
+func RoleInfo(c redis.Conn) (Role, error) {
+	res, err := redis.Values(c.Do("ROLE"))
+	if err != nil {
+		return Role{}, err
+	}
+	return parseRole(res)
+}
+
+func parseRole(res []interface{}) (Role, error) {
+	if len(res) == 0 {
+		return Role{}, errors.New("sentinel: invalid ROLE reply: empty reply")
+	}
+	name, err := redis.String(res[0], nil)
+	if err != nil {
+		return Role{}, err
+	}
+	role := Role{Role: name}
+	invalid := func(reason string) (Role, error) {
+		return Role{}, fmt.Errorf("sentinel: invalid %s ROLE reply: %s", name, reason)
+	}
+
+	switch name {
+	case "master":
+		if len(res) < 3 {
+			return invalid("expected at least three elements")
+		}
+		if role.Offset, err = redis.Int64(res[1], nil); err != nil {
+			return invalid("offset is not numeric")
+		}
+		replicas, err := redis.Values(res[2], nil)
+		if err != nil {
+			return invalid("replicas are not an array")
+		}
+		for _, r := range replicas {
+			s, err := redis.Strings(r, nil)
+			if err != nil || len(s) < 3 {
+				return invalid("malformed replica")
+			}
+			addr, err := parseAddr(s[:2])
+			if err != nil {
+				return Role{}, err
+			}
+			offset, err := strconv.ParseInt(s[2], 10, 64)
+			if err != nil {
+				return invalid("replica offset is not numeric")
+			}
+			role.Replicas = append(role.Replicas, ReplicaOffset{Addr: addr, Offset: offset})
+		}
+	case "slave":
+		if len(res) < 5 {
+			return invalid("expected at least five elements")
+		}
+		if role.Master.Host, err = redis.String(res[1], nil); err != nil {
+			return invalid("master host is not a string")
+		}
+		if role.Master.Port, err = redis.Int(res[2], nil); err != nil {
+			return invalid("master port is not numeric")
+		}
+		if role.LinkState, err = redis.String(res[3], nil); err != nil {
+			return invalid("link state is not a string")
+		}
+		if role.Offset, err = redis.Int64(res[4], nil); err != nil {
+			return invalid("offset is not numeric")
+		}
+	case "sentinel":
+		if len(res) < 2 {
+			return invalid("expected at least two elements")
+		}
+		if role.Masters, err = redis.Strings(res[1], nil); err != nil {
+			return invalid("masters are not an array")
+		}
+	}
+	return role, nil
+}
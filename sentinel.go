@@ -1,8 +1,13 @@
 package sentinel
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"math/rand"
+	"net"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -10,79 +15,499 @@ import (
 	"github.com/gomodule/redigo/redis"
 )
 
+// ErrNoReplicas is returned by the replica pool Dial function if sentinel does
+// not know about any healthy replica of the monitored master.
+var ErrNoReplicas = errors.New("sentinel: no replicas available")
+
+// ErrNoReplicaAvailable is returned by DialReplica if sentinel does not
+// report any healthy replicas. It is the same error as ErrNoReplicas.
+var ErrNoReplicaAvailable = ErrNoReplicas
+
+// ErrMasterLoading is returned by the pool Dial function if master is still
+// loading its dataset after a restart or failover and can not serve commands
+// yet, see Config Pool.CheckLoading. Dial should be retried later.
+var ErrMasterLoading = errors.New("sentinel: master is loading dataset")
+
+// ErrMasterReadOnly is returned by the pool Dial function if master refuses
+// writes, see Config Pool.CheckWritable.
+var ErrMasterReadOnly = errors.New("sentinel: master is read-only")
+
 // Client is an instance of Redis Sentinel client. It supports concurrent
-// querying for master and slave addresses.
+// querying for master and slave addresses. Queries are executed over a small
+// pool of sentinel connections, so independent queries proceed in parallel.
 type Client struct {
-	conn       redis.Conn
-	options    []redis.DialOption
-	addrs      []string
-	activeAddr int
+	idle           map[string][]*sentinelConn
+	closed         bool
+	slots          chan struct{}
+	options        []redis.DialOption
+	clientName     string
+	addrs          []string
+	activeAddr     int
+	allowDangerous bool
+	totalTimeout   time.Duration
+	state          map[string]*sentinelState
+	notifier       *masterNotifier
+	cacheTTL       time.Duration
+	masters        map[string]resolvedAddr
+	lookups        lookupGroup
+	maxConns       int
+	hedge          int
+	hedgeDelay     time.Duration
+	quorum         bool
+	prober         *latencyProber
+	// ranked is addrs ordered by latency once probed, nil otherwise. Query
+	// order and activeAddr follow it if set, see order.
+	ranked []string
+	// breakerThreshold and breakerCooldown configure skipping of failing
+	// sentinels, see WithCircuitBreaker.
+	breakerThreshold int
+	breakerCooldown  time.Duration
+	retryBackoff     RetryBackoff
+	limiter          *lookupLimiter
+	hostnames        HostnameMode
+	resolver         *net.Resolver
+	shuffle          bool
+	stickiness       Stickiness
+	downCheck        bool
+	srv              *srvDiscovery
+	peers            *peerDiscovery
+	connMaxAge       time.Duration
+	reresolver       *reresolver
+	// addrGen counts changes of resolved IPs by sentinel address.
+	addrGen map[string]uint64
 	sync.Mutex
 }
 
 // Config is a configuration struct. It is used by applications using
 // this library to pass Redis Sentinel cluster configuration.
 type Config struct {
-	Master           string
-	Sentinels        []string
+	Master string `json:"master" yaml:"master"`
+	// Sentinels are addresses of the sentinels in host:port form, see
+	// NewClient for the supported address formats.
+	Sentinels        []string `json:"sentinels" yaml:"sentinels"`
 	SentinelTimeouts struct {
-		Connect time.Duration
-		Read    time.Duration
-		Write   time.Duration
-	}
+		Connect time.Duration `json:"connect" yaml:"connect"`
+		Read    time.Duration `json:"read" yaml:"read"`
+		Write   time.Duration `json:"write" yaml:"write"`
+		// Total is an optional deadline for a single lookup spanning all the
+		// attempts across sentinels.
+		Total time.Duration `json:"total" yaml:"total"`
+	} `json:"sentinel_timeouts" yaml:"sentinel_timeouts"`
+	// SentinelKeepAlive is the TCP keep-alive period of sentinel
+	// connections. Zero or NoTimeout disables keep-alive probes.
+	// DefaultConfig sets it to DefaultKeepAlive.
+	SentinelKeepAlive time.Duration `json:"sentinel_keepalive" yaml:"sentinel_keepalive"`
+	// SentinelUsername and SentinelPassword are the credentials used to
+	// authenticate to the sentinels, see WithAuth.
+	SentinelUsername string `json:"sentinel_username" yaml:"sentinel_username"`
+	SentinelPassword string `json:"sentinel_password" yaml:"sentinel_password"`
+	// SentinelClientName is the name of sentinel connections, see
+	// WithClientName. Sentinel connections are not named if empty.
+	SentinelClientName string `json:"sentinel_client_name" yaml:"sentinel_client_name"`
+	// SentinelDialer dials sentinel connections instead of the default
+	// net.Dialer, see WithDialer.
+	SentinelDialer DialFunc `json:"-" yaml:"-"`
+	// SentinelTLS enables TLS for sentinel connections, see WithTLS.
+	SentinelTLS *tls.Config `json:"-" yaml:"-"`
+	// RedisTimeouts configure connections handed out by the pools. Read
+	// and Write can be set to NoTimeout for connections used with blocking
+	// commands, such as BLPOP or SUBSCRIBE. TestOnBorrow role checks still
+	// work on such connections, as ROLE replies are immediate.
 	RedisTimeouts struct {
-		Connect time.Duration
-		Read    time.Duration
-		Write   time.Duration
-	}
+		Connect time.Duration `json:"connect" yaml:"connect"`
+		Read    time.Duration `json:"read" yaml:"read"`
+		Write   time.Duration `json:"write" yaml:"write"`
+	} `json:"redis_timeouts" yaml:"redis_timeouts"`
+	// RedisKeepAlive is the TCP keep-alive period of connections handed
+	// out by the pools, so half-open connections are detected without
+	// waiting for a read to time out. Zero or NoTimeout disables keep-alive
+	// probes. DefaultConfig and NewPoolWithOptions set it to
+	// DefaultKeepAlive, Config passed to NewPool is used as is.
+	RedisKeepAlive time.Duration `json:"redis_keepalive" yaml:"redis_keepalive"`
+	// RedisUsername and RedisPassword are the credentials used to
+	// authenticate connections handed out by the pools. Username requires
+	// redis 6 ACL support, password alone authenticates as the default user.
+	// Passwords are redacted when Config is formatted or encoded.
+	RedisUsername string `json:"redis_username" yaml:"redis_username"`
+	RedisPassword string `json:"redis_password" yaml:"redis_password"`
+	// RedisUseTLS enables TLS for connections handed out by the pools,
+	// configured by RedisTLS if set. Setting RedisTLS enables TLS as well.
+	// Sentinels usually report master and replica addresses as IPs, so
+	// RedisTLS.ServerName should be set to the name in the server
+	// certificate, otherwise the dialed IP is verified.
+	RedisUseTLS bool        `json:"redis_use_tls" yaml:"redis_use_tls"`
+	RedisTLS    *tls.Config `json:"-" yaml:"-"`
+	// ClientName is set with CLIENT SETNAME on every connection handed out
+	// by the pools, to identify them in CLIENT LIST. It must not contain
+	// spaces. Servers with CLIENT command renamed are left unnamed.
+	ClientName string `json:"client_name" yaml:"client_name"`
+	// ShuffleSentinels randomizes the order of the sentinels tried, see
+	// WithShuffledSentinels.
+	ShuffleSentinels bool `json:"shuffle_sentinels" yaml:"shuffle_sentinels"`
+	// SentinelStickiness selects which sentinel is queried first after a
+	// successful query, see WithStickiness.
+	SentinelStickiness Stickiness `json:"sentinel_stickiness" yaml:"sentinel_stickiness"`
+	// MasterDownCheck makes master address lookups fail with
+	// *MasterDownError while sentinel flags the master down, see
+	// WithMasterDownCheck.
+	MasterDownCheck bool `json:"master_down_check" yaml:"master_down_check"`
+	// SentinelConnMaxAge closes sentinel connections older than the
+	// duration, see WithSentinelConnMaxAge. Zero means no limit.
+	SentinelConnMaxAge time.Duration `json:"sentinel_conn_max_age" yaml:"sentinel_conn_max_age"`
+	// SentinelReresolve is the interval of resolving sentinel hostnames
+	// again, see WithReresolve. Zero disables re-resolution.
+	SentinelReresolve time.Duration `json:"sentinel_reresolve" yaml:"sentinel_reresolve"`
+	// SentinelDiscovery enables discovery of other sentinels monitoring the
+	// master in the specified interval, see WithSentinelDiscovery.
+	SentinelDiscovery struct {
+		Interval  time.Duration `json:"interval" yaml:"interval"`
+		DropAfter time.Duration `json:"drop_after" yaml:"drop_after"`
+	} `json:"sentinel_discovery" yaml:"sentinel_discovery"`
+	// SRVRefresh is the interval of looking up again sentinels given as
+	// srv:// addresses, see WithSRVRefresh.
+	SRVRefresh time.Duration `json:"srv_refresh" yaml:"srv_refresh"`
+	// HostnameMode selects how master hostnames announced by sentinels are
+	// handled, see WithHostnames. Resolver is used to resolve them with
+	// HostnameResolve mode, nil uses net.DefaultResolver.
+	HostnameMode HostnameMode  `json:"hostname_mode" yaml:"hostname_mode"`
+	Resolver     *net.Resolver `json:"-" yaml:"-"`
+	// AddressTranslator, if set, rewrites master and replica addresses
+	// announced by sentinel before they are dialed, e.g. to map internal
+	// addresses to the ones reachable through NAT. It is applied to the
+	// addresses of master switch events seen by WatchedPool and AutoPool as
+	// well.
+	AddressTranslator func(Addr) Addr `json:"-" yaml:"-"`
+	// RedisDialer dials connections handed out by the pools instead of the
+	// default net.Dialer. It is passed the addresses resolved via sentinel.
+	RedisDialer DialFunc `json:"-" yaml:"-"`
+	// RedisDialOptions are additional options for dialing connections
+	// handed out by the pools, applied after the options derived from the
+	// rest of Config.
+	RedisDialOptions []redis.DialOption `json:"-" yaml:"-"`
+	// OnConnect is called for every newly dialed connection handed out by
+	// the pools once its role is checked, e.g. to load scripts or enable
+	// client tracking. Error closes the connection and fails the dial.
+	// Connections reused from the pool are not passed to OnConnect again.
+	OnConnect func(redis.Conn) error `json:"-" yaml:"-"`
+	// MinReplicas makes the pool Dial fail with ErrInsufficientReplicas if
+	// master has fewer replicas online, reducing the risk of losing
+	// acknowledged writes in a failover. Replicas lagging more than
+	// MaxReplicaLag behind are not counted, zero accepts any lag. Redis
+	// reports lag in whole seconds. The check costs an INFO round trip per
+	// dialed connection and can be skipped with WithoutReplicaCheck.
+	MinReplicas   int           `json:"min_replicas" yaml:"min_replicas"`
+	MaxReplicaLag time.Duration `json:"max_replica_lag" yaml:"max_replica_lag"`
+	// RedisDB is the database selected on every connection handed out by
+	// the pools. Defaults to 0.
+	RedisDB int `json:"redis_db" yaml:"redis_db"`
+	// MasterCacheTTL enables caching of the resolved master address for the
+	// specified time, see WithMasterCache. Zero disables caching.
+	MasterCacheTTL time.Duration `json:"master_cache_ttl" yaml:"master_cache_ttl"`
+	// Pool configures the redis.Pool returned by NewPool and NewReplicaPool.
+	Pool struct {
+		// MaxIdle is the maximum number of idle connections kept by the
+		// pool. Defaults to 10.
+		MaxIdle int `json:"max_idle" yaml:"max_idle"`
+		// MaxActive limits the number of connections allocated by the pool
+		// at a given time. Zero means no limit.
+		MaxActive int `json:"max_active" yaml:"max_active"`
+		// IdleTimeout closes connections idle for longer than the timeout.
+		// Defaults to 240 seconds.
+		IdleTimeout time.Duration `json:"idle_timeout" yaml:"idle_timeout"`
+		// MaxConnLifetime closes connections older than the duration. Zero
+		// means no limit.
+		MaxConnLifetime time.Duration `json:"max_conn_lifetime" yaml:"max_conn_lifetime"`
+		// Wait makes pool Get wait for a connection to be returned to the
+		// pool once MaxActive limit is reached. GetContext waits until a
+		// connection is available or the context expires. Requires
+		// MaxActive to be set.
+		Wait bool `json:"wait" yaml:"wait"`
+		// BorrowCheck selects the check of borrowed connections. Defaults
+		// to CheckRole.
+		BorrowCheck ConnCheck `json:"borrow_check" yaml:"borrow_check"`
+		// DialCheck selects the check of newly dialed connections.
+		// Defaults to BorrowCheck.
+		DialCheck ConnCheck `json:"dial_check" yaml:"dial_check"`
+		// TestOnBorrowAfter skips the check of borrowed connections used
+		// less than the duration ago. Zero checks every borrowed
+		// connection. DefaultConfig sets it to one second.
+		TestOnBorrowAfter time.Duration `json:"test_on_borrow_after" yaml:"test_on_borrow_after"`
+		// CheckLoading makes the pool Dial fail with ErrMasterLoading if
+		// the newly dialed master is still loading its dataset. It costs
+		// an INFO round trip per dialed connection.
+		CheckLoading bool `json:"check_loading" yaml:"check_loading"`
+		// CheckWritable makes the pool Dial fail with ErrMasterReadOnly if
+		// the newly dialed master is read-only. If WriteProbeKey is set,
+		// the key is written with a short expiration, otherwise
+		// replica-read-only is read with CONFIG GET. Servers with CONFIG
+		// command renamed or disallowed are not checked.
+		CheckWritable bool   `json:"check_writable" yaml:"check_writable"`
+		WriteProbeKey string `json:"write_probe_key" yaml:"write_probe_key"`
+	} `json:"pool" yaml:"pool"`
 }
 
 // NewPool creates redigo/redis.Pool instance based on Config struct provided.
-// Pool instance is safe to be used by redigo library. Error is returned if config is invalid
-func NewPool(conf Config) (*redis.Pool, error) {
+// Pool instance is safe to be used by redigo library. Error is returned if config is invalid.
+// Close must be called to release the pool and its sentinel client.
+func NewPool(conf Config) (*Pool, error) {
+	return NewPoolWithOptions(conf.Master, conf.Sentinels, WithConfig(conf))
+}
+
+// newPool creates master pool using the sentinel Client provided. Config is
+// expected to be validated.
+func newPool(conf Config, sentConn *Client) *redis.Pool {
+	return &redis.Pool{
+		MaxIdle:         conf.poolMaxIdle(),
+		IdleTimeout:     conf.poolIdleTimeout(),
+		MaxActive:       conf.Pool.MaxActive,
+		MaxConnLifetime: conf.Pool.MaxConnLifetime,
+		Wait:            conf.Pool.Wait,
+		DialContext: func(ctx context.Context) (redis.Conn, error) {
+			return dialMaster(ctx, conf, sentConn)
+		},
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			if conf.recentlyUsed(t) {
+				return nil
+			}
+			if err := checkConn(c, "", "master", conf.borrowCheck()); err != nil {
+				sentConn.Invalidate(conf.Master)
+				return wrapError("failed "+string(conf.borrowCheck())+" check", err)
+			}
+			return nil
+		},
+	}
+}
+
+// dialMaster dials the current master resolved using the sentinel Client.
+func dialMaster(ctx context.Context, conf Config, sentConn *Client) (redis.Conn, error) {
+	masterAddr, err := sentConn.MasterAddressContext(ctx, conf.Master)
+	if err != nil {
+		return nil, wrapError("sentinel: get master address", err)
+	}
+	return dialRedis(ctx, conf, conf.translateAddr(masterAddr), "master", func() {
+		sentConn.Invalidate(conf.Master)
+	})
+}
+
+// dialReplica dials healthy replica of the master chosen at random.
+func dialReplica(ctx context.Context, conf Config, sentConn *Client) (redis.Conn, error) {
+	replicas, err := sentConn.replicas(ctx, conf.Master)
+	if err != nil {
+		return nil, wrapError("sentinel: get replica addresses", err)
+	}
+	var replicaAddrs []string
+	for _, r := range replicas {
+		if r.Healthy() {
+			replicaAddrs = append(replicaAddrs, r.Addr().String())
+		}
+	}
+	if len(replicaAddrs) == 0 {
+		return nil, ErrNoReplicas
+	}
+	replicaAddr := replicaAddrs[rand.Intn(len(replicaAddrs))]
+	return dialRedis(ctx, conf, conf.translateAddr(replicaAddr), "slave", nil)
+}
+
+// dialRedis dials redis server at addr configured by Config and checks it has
+// the expected role. The invalidate function, if set, is called when the
+// server is unreachable or has unexpected role, so the address can be looked
+// up again.
+func dialRedis(ctx context.Context, conf Config, addr, role string, invalidate func()) (redis.Conn, error) {
+	if invalidate == nil {
+		invalidate = func() {}
+	}
+	c, err := redis.DialContext(ctx, "tcp", addr, conf.redisDialOptions()...)
+	if isAuthError(err) {
+		return nil, &AuthError{Addr: addr, Err: err}
+	}
+	if err != nil {
+		invalidate()
+		return nil, wrapError("dial error", err)
+	}
+	if err := setClientName(c, conf.ClientName); err != nil {
+		c.Close()
+		return nil, wrapError("dial: set client name", err)
+	}
+	if err := checkConn(c, addr, role, conf.dialCheck()); err != nil {
+		c.Close()
+		if isAuthError(err) {
+			return nil, &AuthError{Addr: addr, Err: err}
+		}
+		invalidate()
+		return nil, wrapError("dial: failed "+string(conf.dialCheck())+" check", err)
+	}
+	if conf.Pool.CheckLoading && role == "master" {
+		if err := checkLoading(c); err != nil {
+			c.Close()
+			return nil, wrapError("dial", err)
+		}
+	}
+	if conf.Pool.CheckWritable && role == "master" {
+		if err := checkWritable(c, conf.Pool.WriteProbeKey); err != nil {
+			c.Close()
+			return nil, wrapError("dial", err)
+		}
+	}
+	if conf.MinReplicas > 0 && role == "master" {
+		if err := checkReplicas(ctx, c, conf.MinReplicas, conf.MaxReplicaLag); err != nil {
+			c.Close()
+			return nil, wrapError("dial: check replicas", err)
+		}
+	}
+	if conf.OnConnect != nil {
+		if err := conf.OnConnect(c); err != nil {
+			c.Close()
+			return nil, wrapError("dial: on connect", err)
+		}
+	}
+	return c, nil
+}
+
+// checkLoading returns ErrMasterLoading if the server is loading its dataset.
+// Servers with INFO command renamed or disabled are not checked.
+func checkLoading(c redis.Conn) error {
+	res, err := redis.String(c.Do("INFO", "persistence"))
+	if rerr, ok := err.(redis.Error); ok && strings.HasPrefix(string(rerr), "LOADING") {
+		return ErrMasterLoading
+	}
+	if isUnknownCommand(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	info, err := parseServerInfo(res)
+	if err != nil {
+		return err
+	}
+	if info.Raw["loading"] == "1" {
+		return ErrMasterLoading
+	}
+	return nil
+}
+
+// checkWritable returns ErrMasterReadOnly if the server refuses writes. Key is
+// written if set, otherwise the read-only setting is read from the server
+// config.
+func checkWritable(c redis.Conn, key string) error {
+	if key != "" {
+		_, err := c.Do("SET", key, "1", "PX", 1000)
+		if isReadOnlyError(err) {
+			return ErrMasterReadOnly
+		}
+		return err
+	}
+	// Servers older than 5.0 know the setting as slave-read-only.
+	for _, param := range []string{"replica-read-only", "slave-read-only"} {
+		res, err := redis.StringMap(c.Do("CONFIG", "GET", param))
+		if isUnknownCommand(err) || isNoPermError(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if v, ok := res[param]; ok {
+			if v == "yes" {
+				return ErrMasterReadOnly
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// isReadOnlyError checks if err is an error reply for a write sent to
+// read-only server.
+func isReadOnlyError(err error) bool {
+	rerr, ok := err.(redis.Error)
+	return ok && strings.HasPrefix(string(rerr), "READONLY")
+}
+
+// isNoPermError checks if err is an error reply for a command disallowed by
+// the user ACL.
+func isNoPermError(err error) bool {
+	rerr, ok := err.(redis.Error)
+	return ok && strings.HasPrefix(string(rerr), "NOPERM")
+}
+
+// setClientName sets the connection name if not empty. Servers with CLIENT
+// command renamed or disabled are left unnamed.
+func setClientName(c redis.Conn, name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, err := c.Do("CLIENT", "SETNAME", name); err != nil && !isUnknownCommand(err) {
+		return err
+	}
+	return nil
+}
+
+// NewReplicaPool creates redigo/redis.Pool instance connecting to the replicas
+// of the master configured in Config. Replica is chosen at random for each new
+// connection. ErrNoReplicas is returned by the pool Dial function if there are
+// no healthy replicas, callers can use it to fall back to master pool. Close
+// must be called to release the pool and its sentinel client.
+func NewReplicaPool(conf Config) (*Pool, error) {
 	if err := validateConfig(conf); err != nil {
 		return nil, err
 	}
 
-	sentConn := NewClient(
-		conf.Sentinels,
-		redis.DialConnectTimeout(conf.SentinelTimeouts.Connect),
-		redis.DialReadTimeout(conf.SentinelTimeouts.Read),
-		redis.DialWriteTimeout(conf.SentinelTimeouts.Write),
-	)
+	sentConn := newSentinelClient(conf)
 
 	sap := &redis.Pool{
-		MaxIdle:     10,
-		IdleTimeout: 240 * time.Second,
-		Dial: func() (redis.Conn, error) {
-			masterAddr, err := sentConn.MasterAddress(conf.Master)
-			if err != nil {
-				return nil, fmt.Errorf("sentinel: get master address: %s", err)
-			}
-			c, err := redis.Dial(
-				"tcp",
-				masterAddr,
-				redis.DialConnectTimeout(conf.RedisTimeouts.Connect),
-				redis.DialReadTimeout(conf.RedisTimeouts.Read),
-				redis.DialWriteTimeout(conf.RedisTimeouts.Write),
-			)
-			if err != nil {
-				return nil, fmt.Errorf("dial error: %s", err)
-			}
-			if err := TestRole(c, "master"); err != nil {
-				return nil, fmt.Errorf("dial: failed role check: %s", err)
-			}
-			return c, err
+		MaxIdle:         conf.poolMaxIdle(),
+		IdleTimeout:     conf.poolIdleTimeout(),
+		MaxActive:       conf.Pool.MaxActive,
+		MaxConnLifetime: conf.Pool.MaxConnLifetime,
+		Wait:            conf.Pool.Wait,
+		DialContext: func(ctx context.Context) (redis.Conn, error) {
+			return dialReplica(ctx, conf, sentConn)
 		},
 		TestOnBorrow: func(c redis.Conn, t time.Time) error {
-			if err := TestRole(c, "master"); err != nil {
-				return fmt.Errorf("failed role check: %s", err)
+			if conf.recentlyUsed(t) {
+				return nil
+			}
+			if err := checkConn(c, "", "slave", conf.borrowCheck()); err != nil {
+				return wrapError("failed "+string(conf.borrowCheck())+" check", err)
 			}
 			return nil
 		},
 	}
 
-	return sap, nil
+	return newOwnedPool(sap, &sharedClient{Client: sentConn}), nil
+}
+
+// newSentinelClient creates sentinel Client used by the pools based on Config.
+func newSentinelClient(conf Config) *Client {
+	opts := []ClientOption{
+		WithDialOptions(
+			redis.DialConnectTimeout(conf.SentinelTimeouts.Connect),
+			redis.DialReadTimeout(conf.SentinelTimeouts.Read),
+			redis.DialWriteTimeout(conf.SentinelTimeouts.Write),
+		),
+		WithDialOptions(dialKeepAlive(conf.SentinelKeepAlive)),
+		WithAuth(conf.SentinelUsername, conf.SentinelPassword),
+		WithClientName(conf.SentinelClientName),
+		WithDialer(conf.SentinelDialer),
+		WithHostnames(conf.HostnameMode, conf.Resolver),
+		WithStickiness(conf.SentinelStickiness),
+		WithSRVRefresh(conf.SRVRefresh),
+		WithSentinelConnMaxAge(conf.SentinelConnMaxAge),
+		WithReresolve(conf.SentinelReresolve),
+		WithSentinelDiscovery(conf.Master, conf.SentinelDiscovery.Interval, conf.SentinelDiscovery.DropAfter),
+		WithTotalTimeout(conf.SentinelTimeouts.Total),
+		WithMasterCache(conf.MasterCacheTTL),
+	}
+	if conf.SentinelTLS != nil {
+		opts = append(opts, WithTLS(conf.SentinelTLS))
+	}
+	if conf.ShuffleSentinels {
+		opts = append(opts, WithShuffledSentinels())
+	}
+	if conf.MasterDownCheck {
+		opts = append(opts, WithMasterDownCheck())
+	}
+	return NewClientWithOptions(conf.Sentinels, opts...)
 }
 
 // NewClient creates a new sentinel client connection. Dial options passed to
@@ -92,111 +517,671 @@ func NewPool(conf Config) (*redis.Pool, error) {
 //
 // Note that in a worst-case scenario, the timeout for performing an
 // operation with a Client client may take (# sentinels) * timeout to try all
-// configured sentinel addresses.
+// configured sentinel addresses, unless the WithTotalTimeout option is used.
+//
+// Addresses are copied, so the caller is free to reuse the slice, and
+// duplicates are dropped. Sentinel addresses without port get the default
+// sentinel port 26379.
+// Sentinels listening on unix domain sockets can be given as
+// unix:///path/to/sentinel.sock. Addresses prefixed with tls:// or rediss://
+// are dialed over TLS and addresses prefixed with redis:// without TLS,
+// regardless of WithTLS option. Addresses of the form
+// srv://_sentinel._tcp.example.com are expanded into the sentinels listed in
+// the DNS SRV records, see WithSRVRefresh.
 func NewClient(addrs []string, options ...redis.DialOption) *Client {
-	return &Client{
-		options: options,
-		addrs:   addrs,
+	return NewClientWithOptions(addrs, WithDialOptions(options...))
+}
+
+// NewClientChecked is like NewClientWithOptions, but returns
+// ErrNoSentinelsConfigured if addrs is empty and error wrapping
+// ErrInvalidAddress or ErrDuplicateAddress naming the offending address.
+func NewClientChecked(addrs []string, opts ...ClientOption) (*Client, error) {
+	if len(addrs) == 0 {
+		return nil, ErrNoSentinelsConfigured
+	}
+	seen := make(map[string]bool, len(addrs))
+	for _, addr := range addrs {
+		norm, err := normalizeAddr(addr)
+		if err != nil {
+			return nil, fmt.Errorf("sentinel: %w", err)
+		}
+		if seen[norm] {
+			return nil, fmt.Errorf("sentinel: %w %q", ErrDuplicateAddress, addr)
+		}
+		seen[norm] = true
+	}
+	return NewClientWithOptions(addrs, opts...), nil
+}
+
+// NewClientWithOptions creates a new sentinel client connection configured
+// with the provided options. See NewClient for notes on dial timeouts.
+// Queries of Client with no sentinel addresses fail with
+// ErrNoSentinelsConfigured, use NewClientChecked to catch it early.
+func NewClientWithOptions(addrs []string, opts ...ClientOption) *Client {
+	sc := &Client{
+		addrs:    dedupAddrs(normalizeAddrs(addrs)),
+		maxConns: defaultMaxConns,
+	}
+	if sc.srv = newSRVDiscovery(sc.addrs); sc.srv != nil {
+		sc.addrs = sc.srv.static
 	}
+	for _, opt := range opts {
+		opt(sc)
+	}
+	sc.slots = make(chan struct{}, sc.maxConns)
+	if sc.shuffle {
+		rand.Shuffle(len(sc.addrs), func(i, j int) {
+			sc.addrs[i], sc.addrs[j] = sc.addrs[j], sc.addrs[i]
+		})
+	}
+	sc.startDiscovery()
+	sc.startReresolve()
+	sc.startPeerDiscovery()
+	sc.startProber()
+	return sc
+}
+
+// Do executes arbitrary redis command on any of the configured sentinel
+// servers using the same retry logic as the rest of Client methods. The raw
+// reply is returned and can be converted using redigo reply helpers.
+func (sc *Client) Do(cmd string, args ...interface{}) (interface{}, error) {
+	return sc.DoContext(context.Background(), cmd, args...)
+}
+
+// DoContext is like Do, but stops trying further sentinels once ctx is done.
+// In that case the returned error wraps the context error.
+func (sc *Client) DoContext(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	return sc.doContext(ctx, cmd, args...)
 }
 
 // do will atempt to execute single redis command on any of the configured
 // sentinel servers. In worst case it will try all sentinel servers exactly once
 // and return last encountered error.
 func (sc *Client) do(cmd string, args ...interface{}) (interface{}, error) {
+	return sc.doContext(context.Background(), cmd, args...)
+}
+
+// doContext is the implementation of do honoring context cancellation.
+func (sc *Client) doContext(parent context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	reply, _, err := sc.doAddr(parent, cmd, args...)
+	return reply, err
+}
+
+// doAddr is like doContext, but also returns the address of the sentinel
+// that replied.
+//
+// Sentinels in TILT mode are skipped, the first of them is used only if none
+// of the other sentinels is reachable. If none of the sentinels could be
+// queried *NoSentinelReachableError is returned.
+func (sc *Client) doAddr(parent context.Context, cmd string, args ...interface{}) (interface{}, string, error) {
 	var err error
 	var reply interface{}
+	var tilted string
+	var skipped []string
+	var failed []AttemptError
+	attempts := 0
+
+	n := sc.sentinelCount()
+	if n == 0 {
+		return nil, "", ErrNoSentinelsConfigured
+	}
+
+	ctx := parent
+	if sc.totalTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(parent, sc.totalTimeout)
+		defer cancel()
+	}
 
-	for i := 0; i < len(sc.addrs); i++ {
-		reply, err = sc.doOnce(cmd, args...)
+	for i := 0; i < n; i++ {
+		if ctxDone(ctx) {
+			return nil, "", sc.contextError(parent, attempts)
+		}
+		addr := sc.activeSentinel()
+		if addr == "" {
+			// Sentinel list was emptied by SetAddrs.
+			break
+		}
+		if !sc.breakerAllows(addr) {
+			skipped = append(skipped, addr)
+			sc.rotate(addr)
+			continue
+		}
+		if !sc.waitRetry(ctx, attempts) {
+			return nil, "", sc.contextError(parent, attempts)
+		}
+		attempts++
+		var tilt bool
+		reply, tilt, err = sc.doOnce(ctx, addr, true, cmd, args...)
+		if err == nil && tilt {
+			if tilted == "" {
+				tilted = addr
+			}
+			err = errTilt
+		}
+		if isAuthError(err) {
+			// Other sentinels are likely to reject the same credentials.
+			return nil, addr, &AuthError{Addr: addr, Err: err}
+		}
 		if err != nil {
+			failed = append(failed, AttemptError{Sentinel: addr, Err: err})
 			// Retry with the next sentinel in the list.
-			sc.activeAddr = (sc.activeAddr + 1) % len(sc.addrs)
+			sc.rotate(addr)
 			continue
 		}
-		break
+		sc.succeeded(addr)
+		return reply, addr, nil
 	}
 
-	return reply, err
+	if len(skipped) > 0 {
+		// Sentinels in cooldown are still tried once all the others
+		// have failed.
+		for _, addr := range skipped {
+			if !sc.waitRetry(ctx, attempts) {
+				break
+			}
+			attempts++
+			var tilt bool
+			if reply, tilt, err = sc.doOnce(ctx, addr, true, cmd, args...); err == nil && tilt {
+				if tilted == "" {
+					tilted = addr
+				}
+				err = errTilt
+			}
+			if err == nil {
+				sc.setActive(addr)
+				sc.succeeded(addr)
+				return reply, addr, nil
+			}
+			if isAuthError(err) {
+				return nil, addr, &AuthError{Addr: addr, Err: err}
+			}
+			failed = append(failed, AttemptError{Sentinel: addr, Err: err})
+		}
+	}
+	if err != nil && tilted != "" && !ctxDone(ctx) {
+		// Unreliable answer is better than no answer at all.
+		if reply, _, err = sc.doOnce(ctx, tilted, false, cmd, args...); err == nil {
+			return reply, tilted, nil
+		}
+		failed = append(failed, AttemptError{Sentinel: tilted, Err: err})
+	}
+	if ctxDone(ctx) {
+		return nil, "", sc.contextError(parent, attempts)
+	}
+
+	return nil, "", unreachable(failed)
 }
 
-// doOnce tries to execute single redis command on the sentinel connection. If
-// necessary it will dial before sending command.
-func (sc *Client) doOnce(cmd string, args ...interface{}) (interface{}, error) {
-	if sc.conn == nil {
-		var err error
-		sc.conn, err = redis.Dial("tcp", sc.addrs[sc.activeAddr], sc.options...)
-		if err != nil {
-			return nil, err
+// waitRetry waits before the next attempt of a query after the given number
+// of failed attempts, see WithRetryBackoff. False is returned if ctx is done
+// before the wait is over.
+func (sc *Client) waitRetry(ctx context.Context, failed int) bool {
+	d := sc.retryBackoff.delay(failed)
+	if d <= 0 {
+		return !ctxDone(ctx)
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// contextError returns error for a query stopped after the given number of
+// attempts since its context is done. It is *TimeoutError if the query run
+// out of the Client total timeout, otherwise it wraps the parent context error.
+func (sc *Client) contextError(parent context.Context, attempts int) error {
+	if !ctxDone(parent) {
+		return &TimeoutError{Attempts: attempts, Sentinels: sc.sentinelCount(), Total: sc.totalTimeout}
+	}
+	if err := parent.Err(); err != nil {
+		return wrapError("sentinel", err)
+	}
+	return wrapError("sentinel", context.DeadlineExceeded)
+}
+
+// ctxDone checks if ctx is done or its deadline has passed. I/O deadlines set
+// from ctx can expire just before ctx itself is marked done, so the deadline
+// is checked as well not to report such failures as unreachable sentinels.
+func ctxDone(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return true
+	}
+	deadline, ok := ctx.Deadline()
+	return ok && !time.Now().Before(deadline)
+}
+
+// doActive executes single redis command on the active sentinel. Unlike do it
+// moves on to the next sentinel only if the active one can not be dialed, so
+// the command is never sent to more than one sentinel server. It is used for
+// commands changing the sentinel state.
+func (sc *Client) doActive(cmd string, args ...interface{}) (interface{}, error) {
+	err := ErrNoSentinelsConfigured
+	ctx := context.Background()
+
+	for i, n := 0, sc.sentinelCount(); i < n; i++ {
+		addr := sc.activeSentinel()
+		if addr == "" {
+			// Sentinel list was emptied by SetAddrs.
+			break
+		}
+		var c *sentinelConn
+		if c, _, err = sc.getConn(ctx, addr, true); isAuthError(err) {
+			return nil, &AuthError{Addr: addr, Err: err}
+		} else if err != nil {
+			sc.rotate(addr)
+			continue
+		}
+		reply, err := redis.DoContext(c.Conn, ctx, cmd, args...)
+		sc.putConn(c, err)
+		if isAuthError(err) {
+			return nil, &AuthError{Addr: addr, Err: err}
 		}
+		if err == nil {
+			sc.succeeded(addr)
+		}
+		return reply, err
 	}
 
-	reply, err := sc.conn.Do(cmd, args...)
+	return nil, err
+}
+
+// doOnce tries to execute single redis command on the connection to the
+// sentinel at addr. If checkTilt is set and sentinel is in TILT mode, the
+// command is not executed and tilt is returned as true.
+//
+// Idle connection might have been closed by the sentinel in the meantime, so
+// if the command fails on a reused connection with EOF or connection reset it
+// is retried once on a freshly dialed connection before giving up on the
+// sentinel. Timeouts are not retried.
+func (sc *Client) doOnce(ctx context.Context, addr string, checkTilt bool, cmd string, args ...interface{}) (reply interface{}, tilt bool, err error) {
+	defer func() { sc.recordAttempt(ctx, addr, err) }()
+
+	c, reused, err := sc.getConn(ctx, addr, true)
 	if err != nil {
-		sc.conn.Close()
-		sc.conn = nil
+		return nil, false, err
 	}
-	return reply, err
+	reply, tilt, err = sc.doConn(ctx, c, checkTilt, cmd, args...)
+	sc.putConn(c, err)
+	if !reused || !isStaleConn(err) || ctxDone(ctx) {
+		return reply, tilt, err
+	}
+
+	if c, _, err = sc.getConn(ctx, addr, false); err != nil {
+		return nil, false, err
+	}
+	reply, tilt, err = sc.doConn(ctx, c, checkTilt, cmd, args...)
+	sc.putConn(c, err)
+	return reply, tilt, err
+}
+
+// doConn executes single redis command on the sentinel connection, see doOnce.
+func (sc *Client) doConn(ctx context.Context, c *sentinelConn, checkTilt bool, cmd string, args ...interface{}) (reply interface{}, tilt bool, err error) {
+	if checkTilt {
+		if tilt, err = sc.inTilt(ctx, c); err != nil || tilt {
+			return nil, tilt, err
+		}
+	}
+	reply, err = redis.DoContext(c.Conn, ctx, cmd, args...)
+	return reply, false, err
 }
 
 // MasterAddress looks up the configuration for a named monitored
-// instance set and returns the master's configuration.
+// instance set and returns the master's configuration. Errors matching
+// ErrMasterUnknown are returned if sentinel does not know the master and
+// errors matching ErrNoSentinelReachable if none of the sentinels could be
+// queried.
 func (sc *Client) MasterAddress(name string) (string, error) {
-	sc.Lock()
-	defer sc.Unlock()
+	return sc.MasterAddressContext(context.Background(), name)
+}
+
+// MasterAddressContext is like MasterAddress, but stops trying further
+// sentinels once ctx is done.
+func (sc *Client) MasterAddressContext(ctx context.Context, name string) (string, error) {
+	addr, err := sc.MasterAddrContext(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	return addr.String(), nil
+}
+
+// MasterAddr looks up the configuration for a named monitored instance set
+// and returns the master's address with host and port separated.
+func (sc *Client) MasterAddr(name string) (Addr, error) {
+	return sc.MasterAddrContext(context.Background(), name)
+}
 
-	res, err := redis.Strings(sc.do("SENTINEL", "get-master-addr-by-name", name))
-	masterAddr := strings.Join(res, ":")
-	return masterAddr, err
+// MasterAddrContext is like MasterAddr, but stops trying further sentinels
+// once ctx is done.
+//
+// Concurrent lookups of the same master are collapsed into a single sentinel
+// query, all the callers share its result.
+func (sc *Client) MasterAddrContext(ctx context.Context, name string) (Addr, error) {
+	return sc.lookupMaster(ctx, name)
+}
+
+// masterAddr resolves the named master address using the cache if enabled.
+func (sc *Client) masterAddr(ctx context.Context, name string) (Addr, error) {
+	if addr, ok := sc.cachedMasterAddr(name); ok {
+		return addr, nil
+	}
+	if sc.limiter != nil {
+		if ok, addr, err := sc.limiter.allow(ctx, name); !ok {
+			return addr, err
+		}
+	}
+
+	var addr Addr
+	var err error
+	if sc.quorum {
+		addr, err = sc.masterAddrQuorum(ctx, name)
+		if err == nil {
+			addr, err = sc.resolveHost(ctx, "", addr)
+		}
+	} else {
+		do := sc.doAddr
+		if sc.hedge > 1 {
+			do = sc.doHedged
+		}
+		for i, n := 0, sc.sentinelCount(); ; i++ {
+			var reply interface{}
+			var sentinel string
+			if sc.downCheck {
+				reply, sentinel, err = do(ctx, "SENTINEL", "master", name)
+				if isNoSuchMaster(err) {
+					err = &MasterUnknownError{Name: name, Sentinel: sentinel}
+				} else if err == nil {
+					addr, err = parseMasterReply(name, sentinel, reply)
+				}
+			} else if reply, sentinel, err = do(ctx, "SENTINEL", "get-master-addr-by-name", name); err == nil {
+				addr, err = parseMasterAddrReply(name, sentinel, reply)
+			}
+			if err != nil {
+				break
+			}
+			var herr *HostnameError
+			if addr, err = sc.resolveHost(ctx, sentinel, addr); errors.As(err, &herr) && i+1 < n && ctx.Err() == nil {
+				// Other sentinels may announce a resolvable address.
+				sc.rotate(sentinel)
+				continue
+			}
+			break
+		}
+		var uerr *NoSentinelReachableError
+		if errors.As(err, &uerr) {
+			uerr.Name = name
+		}
+	}
+	if sc.limiter != nil {
+		sc.limiter.record(name, addr, err)
+	}
+	if err != nil {
+		return Addr{}, err
+	}
+	sc.cacheMasterAddr(name, addr)
+	return addr, nil
+}
+
+// MasterAddressesError is returned by MasterAddresses when some of the master
+// names could not be resolved. Addresses of the rest of the masters are still
+// returned.
+type MasterAddressesError struct {
+	Errors map[string]error
+}
+
+func (e *MasterAddressesError) Error() string {
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	msgs := make([]string, 0, len(names))
+	for _, name := range names {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", name, e.Errors[name]))
+	}
+	return "sentinel: get master addresses: " + strings.Join(msgs, "; ")
 }
 
-// Close will close connection to the sentinel server if one is esatablised.
+// MasterAddresses looks up addresses of multiple named masters at once. All
+// lookups are pipelined over a single sentinel connection. Returned map is
+// keyed by master name, masters that could not be resolved are reported via
+// *MasterAddressesError.
+func (sc *Client) MasterAddresses(names []string) (map[string]string, error) {
+	args := make([][]interface{}, len(names))
+	for i, name := range names {
+		args[i] = []interface{}{"get-master-addr-by-name", name}
+	}
+
+	var err error
+	var replies []interface{}
+
+	n := sc.sentinelCount()
+	if n == 0 {
+		return nil, ErrNoSentinelsConfigured
+	}
+	var addr string
+	var failed []AttemptError
+	for i := 0; i < n; i++ {
+		addr = sc.activeSentinel()
+		if addr == "" {
+			// Sentinel list was emptied by SetAddrs.
+			break
+		}
+		replies, err = sc.pipelineOnce(addr, "SENTINEL", args)
+		if isAuthError(err) {
+			return nil, &AuthError{Addr: addr, Err: err}
+		}
+		if err != nil {
+			failed = append(failed, AttemptError{Sentinel: addr, Err: err})
+			// Retry with the next sentinel in the list.
+			sc.rotate(addr)
+			continue
+		}
+		sc.succeeded(addr)
+		break
+	}
+	if err != nil {
+		return nil, unreachable(failed)
+	}
+
+	addrs := make(map[string]string, len(names))
+	errs := make(map[string]error)
+	for i, name := range names {
+		if err, ok := replies[i].(error); ok {
+			errs[name] = err
+			continue
+		}
+		master, err := parseMasterAddrReply(name, addr, replies[i])
+		if err != nil {
+			errs[name] = err
+			continue
+		}
+		addrs[name] = master.String()
+	}
+	if len(errs) != 0 {
+		return addrs, &MasterAddressesError{Errors: errs}
+	}
+	return addrs, nil
+}
+
+// pipelineOnce sends the same redis command with all sets of arguments over
+// the connection to the sentinel at addr and receives all the replies. Error
+// replies are returned in place of the reply for the respective arguments,
+// error is returned only for connection level failures.
+func (sc *Client) pipelineOnce(addr, cmd string, args [][]interface{}) ([]interface{}, error) {
+	c, reused, err := sc.getConn(context.Background(), addr, true)
+	if err != nil {
+		return nil, err
+	}
+	replies, err := pipeline(c, cmd, args)
+	sc.putConn(c, err)
+	if err == nil || !reused {
+		return replies, err
+	}
+
+	// Retry on a fresh connection in case the idle one went stale.
+	if c, _, err = sc.getConn(context.Background(), addr, false); err != nil {
+		return nil, err
+	}
+	replies, err = pipeline(c, cmd, args)
+	sc.putConn(c, err)
+	return replies, err
+}
+
+func pipeline(c redis.Conn, cmd string, args [][]interface{}) ([]interface{}, error) {
+	for _, a := range args {
+		if err := c.Send(cmd, a...); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.Flush(); err != nil {
+		return nil, err
+	}
+
+	replies := make([]interface{}, len(args))
+	for i := range args {
+		reply, err := c.Receive()
+		if rerr, ok := err.(redis.Error); ok {
+			reply, err = rerr, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		replies[i] = reply
+	}
+	return replies, nil
+}
+
+// Ping checks if any of the configured sentinel servers is reachable. Nil is
+// returned if a sentinel replied with PONG.
+func (sc *Client) Ping() error {
+	res, err := redis.String(sc.do("PING"))
+	if err != nil {
+		return err
+	}
+	if res != "PONG" {
+		return fmt.Errorf("sentinel: unexpected ping reply: %q", res)
+	}
+	return nil
+}
+
+// Close will close idle connections to the sentinel servers and stop master
+// change notifications registered with OnMasterChange. Connections in use are
+// closed once the queries using them complete.
 func (sc *Client) Close() {
 	sc.Lock()
 	defer sc.Unlock()
 
-	if sc.conn != nil {
-		sc.conn.Close()
-		sc.conn = nil
+	sc.closed = true
+	if sc.notifier != nil {
+		sc.notifier.cancel()
 	}
+	if sc.prober != nil && sc.prober.cancel != nil {
+		sc.prober.cancel()
+	}
+	if sc.srv != nil && sc.srv.cancel != nil {
+		sc.srv.cancel()
+	}
+	if sc.reresolver != nil && sc.reresolver.cancel != nil {
+		sc.reresolver.cancel()
+	}
+	if sc.peers != nil && sc.peers.cancel != nil {
+		sc.peers.cancel()
+	}
+	for _, conns := range sc.idle {
+		for _, c := range conns {
+			c.Close()
+		}
+	}
+	sc.idle = nil
 }
 
 // TestRole is a convenience function for checking redis server role. It
 // uses the ROLE command introduced in redis 2.8.12. Nil is returned if server
-// role matches the expected role.
+// role matches the expected role, *RoleError is returned if it does not.
+// Roles "slave" and "replica" are treated as the same role, whichever of them
+// is expected or reported by the server. Role is read from the INFO
+// replication section if the server does not know the ROLE command, as some
+// managed redis providers rename or disable it.
 //
 // It is recommended by the redis client guidelines to test the role of any
 // newly established connection before use.
 func TestRole(c redis.Conn, expectedRole string) error {
-	res, err := redis.Values(c.Do("ROLE"))
+	role, err := serverRole(c)
 	if err != nil {
 		return err
 	}
-	role, err := redis.String(res[0], nil)
+	if normalizeRole(role) != normalizeRole(expectedRole) {
+		return &RoleError{Expected: expectedRole, Actual: role}
+	}
+	return nil
+}
+
+// serverRole returns the role reported by the redis server.
+func serverRole(c redis.Conn) (string, error) {
+	res, err := redis.Values(c.Do("ROLE"))
+	if isUnknownCommand(err) {
+		return infoRole(c)
+	}
 	if err != nil {
-		return err
+		return "", err
 	}
-	if role != expectedRole {
-		return errors.New("role check failed")
+	if len(res) == 0 {
+		return "", errors.New("role check failed: empty ROLE reply")
 	}
-	return nil
+	return redis.String(res[0], nil)
 }
 
-func validateConfig(conf Config) error {
-	if conf.Master == "" {
-		return errors.New("master is not set")
+// infoRole returns the role line of the INFO replication section.
+func infoRole(c redis.Conn) (string, error) {
+	res, err := redis.String(c.Do("INFO", "replication"))
+	if err != nil {
+		return "", err
 	}
-	if len(conf.Sentinels) == 0 {
-		return errors.New("sentinel array is not set")
+	info, err := parseServerInfo(res)
+	if err != nil {
+		return "", err
 	}
-	if conf.SentinelTimeouts.Connect.Nanoseconds() == 0 ||
-		conf.SentinelTimeouts.Read.Nanoseconds() == 0 ||
-		conf.SentinelTimeouts.Write.Nanoseconds() == 0 {
-		return errors.New("sentinel timeouts are not set")
+	role := info.Raw["role"]
+	if role == "" {
+		return "", errors.New("role check failed: no role in INFO reply")
 	}
-	if conf.RedisTimeouts.Connect.Nanoseconds() == 0 {
-		return errors.New("redis connect timeout is not set")
+	return role, nil
+}
+
+// normalizeRole maps the "replica" role alias used by newer redis versions
+// to "slave" reported by the ROLE command.
+func normalizeRole(role string) string {
+	if role == "replica" {
+		return "slave"
 	}
+	return role
+}
 
-	return nil
+// checkConn verifies connection to redis server at addr using the check
+// selected.
+func checkConn(c redis.Conn, addr, role string, check ConnCheck) error {
+	switch check {
+	case CheckPing:
+		_, err := c.Do("PING")
+		return err
+	case CheckNone:
+		return nil
+	}
+	return testRoleAt(c, addr, role)
+}
+
+// testRoleAt is like TestRole, but includes the server address in the
+// returned *RoleError.
+func testRoleAt(c redis.Conn, addr, expectedRole string) error {
+	err := TestRole(c, expectedRole)
+	if rerr, ok := err.(*RoleError); ok {
+		rerr.Addr = addr
+	}
+	return err
 }
@@ -0,0 +1,501 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestMasterAddressFormats(t *testing.T) {
+	tests := []struct {
+		host, port string
+		want       string
+	}{
+		{"10.0.0.1", "6379", "10.0.0.1:6379"},
+		{"fd00::1", "6379", "[fd00::1]:6379"},
+		{"::1", "6380", "[::1]:6380"},
+		{"redis-1.example.com", "6379", "redis-1.example.com:6379"},
+	}
+	for _, tt := range tests {
+		s := newFake(t, func(args []string) string {
+			if strings.EqualFold(args[0], "SENTINEL") {
+				return bulkArr(tt.host, tt.port)
+			}
+			return basicHandler(args)
+		})
+		c := NewClient([]string{s.addr()})
+		got, err := c.MasterAddress("mymaster")
+		c.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.host, err)
+		}
+		if got != tt.want {
+			t.Errorf("MasterAddress() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestPoolDialIPv6Master(t *testing.T) {
+	ln, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skip("IPv6 loopback not available:", err)
+	}
+	master := serveFake(t, ln, func(args []string) string {
+		if strings.EqualFold(args[0], "ROLE") {
+			return masterRole
+		}
+		return "+OK\r\n"
+	}, 0)
+	s := newFake(t, pointTo(master.addr()))
+
+	p, err := NewPool(testConfig(s.addr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	c, err := p.DialContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+}
+
+func TestPoolDialNetError(t *testing.T) {
+	s := newFake(t, pointTo(closedAddr(t)))
+	p, err := NewPool(testConfig(s.addr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	_, err = p.DialContext(context.Background())
+	var nerr net.Error
+	if !errors.As(err, &nerr) {
+		t.Fatalf("DialContext() error = %#v, want net.Error in chain", err)
+	}
+	if !strings.HasPrefix(err.Error(), "dial error: ") {
+		t.Errorf("error message %q changed", err)
+	}
+}
+
+func TestPoolCheckErrorChain(t *testing.T) {
+	var broken int32
+	master := newFake(t, func(args []string) string {
+		if strings.EqualFold(args[0], "ROLE") {
+			if atomic.LoadInt32(&broken) == 1 {
+				return closeAfter
+			}
+			return masterRole
+		}
+		return "+OK\r\n"
+	})
+	s := newFake(t, pointTo(master.addr()))
+	p, err := NewPool(testConfig(s.addr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	c, err := p.DialContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	atomic.StoreInt32(&broken, 1)
+	err = p.TestOnBorrow(c, time.Time{})
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("TestOnBorrow() error = %#v, want io.EOF in chain", err)
+	}
+	if !strings.HasPrefix(err.Error(), "failed role check: ") {
+		t.Errorf("error message %q changed", err)
+	}
+}
+
+func TestNoSentinelsConfigured(t *testing.T) {
+	if _, err := NewClientChecked(nil); err != ErrNoSentinelsConfigured {
+		t.Fatalf("NewClientChecked(nil) error = %v, want ErrNoSentinelsConfigured", err)
+	}
+	if _, err := NewPool(Config{Master: "mymaster"}); err == nil {
+		t.Fatal("NewPool accepted empty Sentinels")
+	}
+
+	modes := []struct {
+		name string
+		opts []ClientOption
+	}{
+		{"default", nil},
+		{"hedged", []ClientOption{WithHedgedRequests(2, time.Millisecond)}},
+		{"quorum", []ClientOption{WithQuorumAgreement()}},
+	}
+	for _, m := range modes {
+		c := NewClientWithOptions(nil, m.opts...)
+		addr, err := c.MasterAddress("mymaster")
+		if !errors.Is(err, ErrNoSentinelsConfigured) || addr != "" {
+			t.Errorf("%s: MasterAddress() = %q, %v, want ErrNoSentinelsConfigured", m.name, addr, err)
+		}
+		if _, err := c.MyID(); !errors.Is(err, ErrNoSentinelsConfigured) {
+			t.Errorf("%s: MyID() error = %v", m.name, err)
+		}
+		if err := c.Failover("mymaster"); !errors.Is(err, ErrNoSentinelsConfigured) {
+			t.Errorf("%s: Failover() error = %v", m.name, err)
+		}
+		if _, err := c.WatchMaster(context.Background(), "mymaster"); !errors.Is(err, ErrNoSentinelsConfigured) {
+			t.Errorf("%s: WatchMaster() error = %v", m.name, err)
+		}
+		c.Close()
+	}
+}
+
+func TestPoolDialEmptiedSentinels(t *testing.T) {
+	s, _ := newFakeMaster(t)
+	c := NewClient([]string{s.addr()})
+	defer c.Close()
+	p, err := NewPoolWithClient(c, "mymaster", testConfig(s.addr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	c.SetAddrs(nil)
+	if addr, err := c.MasterAddress("mymaster"); !errors.Is(err, ErrNoSentinelsConfigured) || addr != "" {
+		t.Errorf("MasterAddress() = %q, %v, want ErrNoSentinelsConfigured", addr, err)
+	}
+	if _, err := p.DialContext(context.Background()); !errors.Is(err, ErrNoSentinelsConfigured) {
+		t.Errorf("DialContext() error = %v, want ErrNoSentinelsConfigured", err)
+	}
+}
+
+func TestRedisDialOptions(t *testing.T) {
+	s, cmds := newFakeMaster(t)
+	conf := testConfig(s.addr())
+	var mu sync.Mutex
+	var dialed []string
+	conf.RedisDialOptions = []redis.DialOption{
+		redis.DialKeepAlive(time.Second),
+		redis.DialDatabase(2),
+		redis.DialClientName("svc"),
+		redis.DialContextFunc(func(ctx context.Context, network, addr string) (net.Conn, error) {
+			mu.Lock()
+			dialed = append(dialed, addr)
+			mu.Unlock()
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		}),
+	}
+	p, err := NewPool(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	c, err := p.DialContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	sc := NewClient([]string{s.addr()})
+	defer sc.Close()
+	master, err := sc.MasterAddress("mymaster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(dialed) != 1 || dialed[0] != master {
+		t.Errorf("dialed %q, want master %s", dialed, master)
+	}
+	got := cmds()
+	if len(got) < 2 || got[0] != "CLIENT SETNAME svc" || got[1] != "SELECT 2" {
+		t.Errorf("master received %q, want CLIENT SETNAME and SELECT first", got)
+	}
+}
+
+func TestRedisDialOptionsOverrideTimeouts(t *testing.T) {
+	s := newFake(t, pointTo(silentFake(t).addr()))
+	conf := testConfig(s.addr())
+	conf.RedisDialOptions = []redis.DialOption{redis.DialReadTimeout(20 * time.Millisecond)}
+	p, err := NewPool(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	start := time.Now()
+	_, err = p.DialContext(context.Background())
+	if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+		t.Fatalf("DialContext() error = %v, want timeout", err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("dial took %v, read timeout option not applied", d)
+	}
+}
+
+func TestOnConnect(t *testing.T) {
+	var mu sync.Mutex
+	var first []string
+	master := newFake(t, func(args []string) string {
+		mu.Lock()
+		if len(first) < 2 {
+			first = append(first, strings.Join(args, " "))
+		}
+		mu.Unlock()
+		return roleMaster(args)
+	})
+	s := newFake(t, pointTo(master.addr()))
+	var calls int32
+	errFlaky := errors.New("flaky")
+	p, err := NewPoolWithOptions("mymaster", []string{s.addr()}, WithConfig(testConfig(s.addr())),
+		WithOnConnect(func(c redis.Conn) error {
+			if atomic.AddInt32(&calls, 1)%2 == 0 {
+				return errFlaky
+			}
+			_, err := c.Do("CLIENT", "TRACKING", "on")
+			return err
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	for i := 0; i < 3; i++ {
+		c := p.Get()
+		if _, err := c.Do("PING"); err != nil {
+			t.Fatal(err)
+		}
+		c.Close()
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("OnConnect called %d times for borrowed connections, want 1", n)
+	}
+	mu.Lock()
+	if len(first) != 2 || first[0] != "ROLE" || first[1] != "CLIENT TRACKING on" {
+		t.Errorf("first commands %q, want OnConnect after the role check", first)
+	}
+	mu.Unlock()
+
+	if _, err := p.DialContext(context.Background()); !errors.Is(err, errFlaky) {
+		t.Fatalf("DialContext() error = %v, want OnConnect error", err)
+	}
+	waitFor(t, "connection failing OnConnect closed", func() bool {
+		return master.openConns() == 1
+	})
+	c, err := p.DialContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if n := master.calls("CLIENT TRACKING on"); n != 2 {
+		t.Errorf("CLIENT TRACKING sent %d times, want 2", n)
+	}
+}
+
+func TestNewClientCopiesAddrs(t *testing.T) {
+	dead := newFake(t, func(args []string) string { return closeAfter })
+	live := newFake(t, basicHandler)
+	addrs := []string{dead.addr(), live.addr(), dead.addr(), live.addr()}
+	c := NewClient(addrs)
+	defer c.Close()
+
+	if got, want := c.Addrs(), []string{dead.addr(), live.addr()}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Addrs() = %q, want %q", got, want)
+	}
+
+	// The caller reusing its slice must not race with the lookups.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			addrs[i%len(addrs)] = "10.0.0.1:1"
+			addrs = append(addrs[:0], live.addr(), dead.addr())
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		if _, err := c.MasterAddress("mymaster"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+
+	if got, want := c.Addrs(), []string{dead.addr(), live.addr()}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Addrs() = %q after caller changed its slice, want %q", got, want)
+	}
+	if n := dead.calls(getMaster); n > 1 {
+		t.Errorf("dead sentinel queried %d times, want at most once", n)
+	}
+}
+
+// dialFake dials a fake server started with the handler.
+func dialFake(t *testing.T, h func(args []string) string) redis.Conn {
+	c, err := redis.Dial("tcp", newFake(t, h).addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestRoleAlias(t *testing.T) {
+	tests := []struct {
+		server, expected string
+		ok               bool
+	}{
+		{"slave", "slave", true},
+		{"slave", "replica", true},
+		{"replica", "slave", true},
+		{"replica", "replica", true},
+		{"master", "master", true},
+		{"master", "slave", false},
+		{"master", "replica", false},
+		{"replica", "master", false},
+	}
+	for _, tt := range tests {
+		c := dialFake(t, func(args []string) string { return bulkArr(tt.server) })
+		err := TestRole(c, tt.expected)
+		var rerr *RoleError
+		switch {
+		case tt.ok && err != nil:
+			t.Errorf("server %s, expected %s: %v", tt.server, tt.expected, err)
+		case !tt.ok && (!errors.As(err, &rerr) || rerr.Actual != tt.server || rerr.Expected != tt.expected):
+			t.Errorf("server %s, expected %s: error = %v, want *RoleError", tt.server, tt.expected, err)
+		}
+	}
+}
+
+// renamedRole answers as redis server reporting role only in INFO
+// replication, with ROLE command renamed.
+func renamedRole(role string) func(args []string) string {
+	return func(args []string) string {
+		switch strings.ToUpper(args[0]) {
+		case "ROLE":
+			return "-ERR unknown command 'ROLE', with args beginning with: \r\n"
+		case "INFO":
+			if len(args) != 2 || args[1] != "replication" {
+				return "-ERR unexpected section\r\n"
+			}
+			if role == "" {
+				return bulk("# Replication\r\n")
+			}
+			return bulk("# Replication\r\nrole:" + role + "\r\nconnected_slaves:0\r\n")
+		}
+		return "+OK\r\n"
+	}
+}
+
+func TestRoleInfoFallback(t *testing.T) {
+	tests := []struct {
+		server, expected string
+		ok               bool
+	}{
+		{"master", "master", true},
+		{"slave", "replica", true},
+		{"slave", "slave", true},
+		{"slave", "master", false},
+		{"master", "slave", false},
+	}
+	for _, tt := range tests {
+		err := TestRole(dialFake(t, renamedRole(tt.server)), tt.expected)
+		var rerr *RoleError
+		switch {
+		case tt.ok && err != nil:
+			t.Errorf("server %s, expected %s: %v", tt.server, tt.expected, err)
+		case !tt.ok && (!errors.As(err, &rerr) || rerr.Actual != tt.server):
+			t.Errorf("server %s, expected %s: error = %v, want *RoleError", tt.server, tt.expected, err)
+		}
+	}
+
+	err := TestRole(dialFake(t, renamedRole("")), "master")
+	var rerr *RoleError
+	if err == nil || errors.As(err, &rerr) {
+		t.Errorf("TestRole() error = %v for INFO without role", err)
+	}
+}
+
+func TestPoolDialRenamedRole(t *testing.T) {
+	master := newFake(t, renamedRole("master"))
+	s := newFake(t, pointTo(master.addr()))
+	p, err := NewPool(testConfig(s.addr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	c, err := p.DialContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if n := master.calls("INFO replication"); n != 1 {
+		t.Errorf("INFO replication sent %d times, want 1", n)
+	}
+}
+
+func TestMasterAddresses(t *testing.T) {
+	s := newFake(t, func(args []string) string {
+		if len(args) == 3 && strings.EqualFold(args[1], "get-master-addr-by-name") {
+			switch args[2] {
+			case "mymaster":
+				return bulkArr("10.0.0.1", "6379")
+			case "other":
+				return bulkArr("10.0.0.2", "6380")
+			}
+			return "*-1\r\n"
+		}
+		return basicHandler(args)
+	})
+	c := NewClient([]string{closedAddr(t), s.addr()})
+	defer c.Close()
+
+	addrs, err := c.MasterAddresses([]string{"mymaster", "other", "missing"})
+	var merr *MasterAddressesError
+	if !errors.As(err, &merr) {
+		t.Fatalf("MasterAddresses() error = %v, want *MasterAddressesError", err)
+	}
+	if want := map[string]string{"mymaster": "10.0.0.1:6379", "other": "10.0.0.2:6380"}; !reflect.DeepEqual(addrs, want) {
+		t.Errorf("MasterAddresses() = %v, want %v", addrs, want)
+	}
+	if len(merr.Errors) != 1 || !errors.Is(merr.Errors["missing"], ErrMasterUnknown) {
+		t.Errorf("MasterAddresses() errors = %v, want only missing unknown", merr.Errors)
+	}
+	if n := s.dialed(); n != 1 {
+		t.Errorf("sentinel dialed %d times, want lookups pipelined over one connection", n)
+	}
+	if got := c.ActiveSentinel(); got != s.addr() {
+		t.Errorf("active sentinel %s, want %s", got, s.addr())
+	}
+}
+
+func TestShuffledSentinels(t *testing.T) {
+	addrs := []string{"10.0.0.1:26379", "10.0.0.2:26379", "10.0.0.3:26379", "10.0.0.4:26379", "10.0.0.5:26379"}
+	c := NewClientWithOptions(addrs)
+	defer c.Close()
+	if got := c.Addrs(); !reflect.DeepEqual(got, addrs) {
+		t.Fatalf("Addrs() = %q, want configured order %q", got, addrs)
+	}
+
+	shuffled := false
+	for i := 0; i < 20; i++ {
+		c := NewClientWithOptions(addrs, WithShuffledSentinels())
+		got := c.Addrs()
+		c.Close()
+		if !reflect.DeepEqual(got, addrs) {
+			shuffled = true
+		}
+		sorted := append([]string(nil), got...)
+		sort.Strings(sorted)
+		if !reflect.DeepEqual(sorted, addrs) {
+			t.Fatalf("Addrs() = %q, want permutation of %q", got, addrs)
+		}
+	}
+	if !shuffled {
+		t.Error("sentinels of 20 clients kept the configured order")
+	}
+}
@@ -0,0 +1,74 @@
+package sentinel
+
+import (
+	"context"
+	"sync"
+)
+
+// lookupGroup collapses concurrent master lookups of the same name into a
+// single in-flight lookup.
+type lookupGroup struct {
+	mu    sync.Mutex
+	calls map[string]*lookupCall
+}
+
+// lookupCall is an in-flight master lookup.
+type lookupCall struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+	addr    Addr
+	err     error
+}
+
+// lookupMaster resolves the named master address, joining the in-flight
+// lookup if there is one. The lookup runs detached from the contexts of the
+// callers, each caller stops waiting once its own context is done. The lookup
+// itself is cancelled when all the callers stopped waiting.
+func (sc *Client) lookupMaster(ctx context.Context, name string) (Addr, error) {
+	g := &sc.lookups
+
+	g.mu.Lock()
+	call, ok := g.calls[name]
+	if !ok {
+		lookupCtx, cancel := context.WithCancel(context.Background())
+		call = &lookupCall{
+			done:   make(chan struct{}),
+			cancel: cancel,
+		}
+		if g.calls == nil {
+			g.calls = make(map[string]*lookupCall)
+		}
+		g.calls[name] = call
+
+		go func() {
+			call.addr, call.err = sc.masterAddr(lookupCtx, name)
+			g.mu.Lock()
+			if g.calls[name] == call {
+				delete(g.calls, name)
+			}
+			g.mu.Unlock()
+			cancel()
+			close(call.done)
+		}()
+	}
+	call.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.addr, call.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			call.cancel()
+			// Newer callers must not join the cancelled lookup.
+			if g.calls[name] == call {
+				delete(g.calls, name)
+			}
+		}
+		g.mu.Unlock()
+		return Addr{}, wrapError("sentinel", ctx.Err())
+	}
+}
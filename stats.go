@@ -0,0 +1,132 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// tiltCheckInterval is the time TILT mode status of the sentinel is cached
+// for before checking it again.
+const tiltCheckInterval = 5 * time.Second
+
+// errTilt is used internally to mark sentinels skipped due to TILT mode.
+var errTilt = errors.New("sentinel: in TILT mode")
+
+// sentinelState holds runtime state of a single sentinel server tracked by
+// Client.
+type sentinelState struct {
+	tilt        bool
+	tiltChecked time.Time
+
+	latency        time.Duration
+	latencyErr     error
+	latencyChecked time.Time
+
+	failures      int
+	cooldownUntil time.Time
+	// probing is set while a single query probes the sentinel after the
+	// cooldown.
+	probing bool
+}
+
+// SentinelStats is a runtime status of a single sentinel server as seen by
+// Client.
+type SentinelStats struct {
+	Addr string
+	// Active is true for the sentinel Client is currently talking to.
+	Active bool
+	// Tilt is true if the sentinel was in TILT mode when last checked.
+	// Sentinels in TILT mode are bypassed while others are reachable.
+	Tilt bool
+	// TiltChecked is the time of the last TILT mode check, zero if the
+	// sentinel was never checked.
+	TiltChecked time.Time
+	// Latency is the moving average of PING round trip time measured when
+	// latency probing is enabled, zero if unknown or the last probe failed.
+	Latency time.Duration
+	// LatencyErr is the error of the last latency probe, if it failed.
+	LatencyErr error
+	// LatencyChecked is the time of the last latency probe, zero if the
+	// sentinel was never probed.
+	LatencyChecked time.Time
+	// Failures is the number of consecutive failed queries to the sentinel.
+	Failures int
+	// CooldownUntil is the time until which the sentinel is skipped after
+	// failing repeatedly, see WithCircuitBreaker. Zero if the sentinel is not
+	// in cooldown.
+	CooldownUntil time.Time
+}
+
+// Stats returns runtime status of all the configured sentinel servers in the
+// order of preference.
+func (sc *Client) Stats() []SentinelStats {
+	sc.Lock()
+	defer sc.Unlock()
+
+	stats := make([]SentinelStats, 0, len(sc.addrs))
+	for i, addr := range sc.order() {
+		st := sc.sentinelState(addr)
+		stats = append(stats, SentinelStats{
+			Addr:           addr,
+			Active:         i == sc.activeAddr,
+			Tilt:           st.tilt,
+			TiltChecked:    st.tiltChecked,
+			Latency:        st.latency,
+			LatencyErr:     st.latencyErr,
+			LatencyChecked: st.latencyChecked,
+			Failures:       st.failures,
+			CooldownUntil:  st.cooldownUntil,
+		})
+	}
+	return stats
+}
+
+// sentinelState returns runtime state of the sentinel, creating it if
+// necessary.
+func (sc *Client) sentinelState(addr string) *sentinelState {
+	if sc.state == nil {
+		sc.state = make(map[string]*sentinelState)
+	}
+	st, ok := sc.state[addr]
+	if !ok {
+		st = &sentinelState{}
+		sc.state[addr] = st
+	}
+	return st
+}
+
+// inTilt checks if the sentinel connected over c is in TILT mode. The result
+// is cached for tiltCheckInterval. Sentinels refusing to execute INFO are
+// considered not to be in TILT mode.
+func (sc *Client) inTilt(ctx context.Context, c *sentinelConn) (bool, error) {
+	sc.Lock()
+	st := sc.sentinelState(c.addr)
+	if time.Since(st.tiltChecked) < tiltCheckInterval {
+		tilt := st.tilt
+		sc.Unlock()
+		return tilt, nil
+	}
+	sc.Unlock()
+
+	res, err := redis.String(redis.DoContext(c.Conn, ctx, "INFO", "sentinel"))
+	if _, ok := err.(redis.Error); ok {
+		res, err = "", nil
+	}
+	if err != nil {
+		return false, err
+	}
+	info, err := parseServerInfo(res)
+	if err != nil {
+		return false, err
+	}
+
+	sc.Lock()
+	defer sc.Unlock()
+
+	st.tilt = info.Tilt
+	st.tiltChecked = time.Now()
+	return st.tilt, nil
+}
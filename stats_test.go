@@ -0,0 +1,57 @@
+package sentinel
+
+import (
+	"strings"
+	"testing"
+)
+
+// tiltHandler answers as sentinel in TILT mode.
+func tiltHandler(args []string) string {
+	if strings.EqualFold(args[0], "INFO") {
+		return bulk("# Sentinel\r\nsentinel_tilt:1\r\n")
+	}
+	return basicHandler(args)
+}
+
+func TestTiltSkippedStats(t *testing.T) {
+	tilted, healthy := newFake(t, tiltHandler), newFake(t, basicHandler)
+	down := closedAddr(t)
+	c := NewClient([]string{tilted.addr(), down, healthy.addr()})
+	defer c.Close()
+
+	if _, err := c.MasterAddress("mymaster"); err != nil {
+		t.Fatal(err)
+	}
+	if n := tilted.calls(getMaster); n != 0 {
+		t.Errorf("sentinel in TILT mode queried %d times", n)
+	}
+
+	stats := c.Stats()
+	if len(stats) != 3 {
+		t.Fatalf("Stats() = %+v, want 3 sentinels", stats)
+	}
+	if st := stats[0]; st.Addr != tilted.addr() || !st.Tilt || st.TiltChecked.IsZero() || st.Active {
+		t.Errorf("tilted sentinel stats %+v", st)
+	}
+	if st := stats[1]; st.Addr != down || st.Tilt || st.Active {
+		t.Errorf("unreachable sentinel stats %+v", st)
+	}
+	if st := stats[2]; st.Addr != healthy.addr() || st.Tilt || !st.Active {
+		t.Errorf("healthy sentinel stats %+v", st)
+	}
+}
+
+func TestTiltOnlySentinel(t *testing.T) {
+	s := newFake(t, tiltHandler)
+	c := NewClient([]string{s.addr()})
+	defer c.Close()
+
+	// Unreliable answer is returned if all the sentinels are in TILT mode.
+	addr, err := c.MasterAddress("mymaster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr != "[fd00::1]:6379" {
+		t.Errorf("MasterAddress() = %q, want [fd00::1]:6379", addr)
+	}
+}
@@ -0,0 +1,45 @@
+package sentinel
+
+// Stickiness selects which sentinel is queried first after a successful
+// query, see WithStickiness.
+type Stickiness string
+
+// Stickiness policies. StickinessSticky is used by default.
+const (
+	// StickinessSticky keeps querying the sentinel that answered last, so
+	// Client moves on to the next sentinel only once the active one fails.
+	StickinessSticky Stickiness = "sticky"
+	// StickinessPreferFirst returns to the first sentinel in the list after
+	// every successful query, so transient failures do not move Client away
+	// from it for good.
+	StickinessPreferFirst Stickiness = "prefer-first"
+	// StickinessRoundRobin moves on to the next sentinel after every query,
+	// spreading the queries across all the sentinels.
+	StickinessRoundRobin Stickiness = "round-robin"
+)
+
+// succeeded applies the stickiness policy after a successful query to the
+// sentinel at addr.
+func (sc *Client) succeeded(addr string) {
+	sc.Lock()
+	defer sc.Unlock()
+
+	if len(sc.addrs) == 0 {
+		return
+	}
+	order := sc.order()
+	switch sc.stickiness {
+	case StickinessPreferFirst:
+		// First in the configured order, regardless of latency ranking.
+		for i, a := range order {
+			if a == sc.addrs[0] {
+				sc.activeAddr = i
+			}
+		}
+	case StickinessRoundRobin:
+		// Nothing is done if other query has already rotated away.
+		if order[sc.activeAddr] == addr {
+			sc.activeAddr = (sc.activeAddr + 1) % len(order)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package sentinel
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestStickiness(t *testing.T) {
+	tests := []struct {
+		policy Stickiness
+		// active is the active sentinel after each lookup, the first lookup
+		// fails on sentinel a once.
+		active []string
+	}{
+		{StickinessSticky, []string{"b", "b", "b"}},
+		{StickinessPreferFirst, []string{"a", "a", "a"}},
+		{StickinessRoundRobin, []string{"c", "a", "b"}},
+		{"", []string{"b", "b", "b"}},
+	}
+	for _, tt := range tests {
+		fail := int32(1)
+		a := newFake(t, func(args []string) string {
+			if strings.EqualFold(args[0], "SENTINEL") && atomic.CompareAndSwapInt32(&fail, 1, 0) {
+				return "-ERR transient\r\n"
+			}
+			return basicHandler(args)
+		})
+		b, c := newFake(t, basicHandler), newFake(t, basicHandler)
+		names := map[string]string{a.addr(): "a", b.addr(): "b", c.addr(): "c"}
+
+		sc := NewClientWithOptions([]string{a.addr(), b.addr(), c.addr()}, WithStickiness(tt.policy))
+		for i, want := range tt.active {
+			if _, err := sc.MasterAddress("mymaster"); err != nil {
+				t.Fatalf("%q lookup %d: %v", tt.policy, i, err)
+			}
+			if got := names[sc.ActiveSentinel()]; got != want {
+				t.Errorf("%q lookup %d: active sentinel %s, want %s", tt.policy, i, got, want)
+			}
+		}
+		sc.Close()
+	}
+}
+
+func TestValidateStickiness(t *testing.T) {
+	conf := testConfig("127.0.0.1:26379")
+	for _, policy := range []Stickiness{"", StickinessSticky, StickinessPreferFirst, StickinessRoundRobin} {
+		conf.SentinelStickiness = policy
+		if err := validateConfig(conf); err != nil {
+			t.Errorf("%q: %v", policy, err)
+		}
+	}
+	conf.SentinelStickiness = "random"
+	var cerr *ConfigError
+	if err := validateConfig(conf); !errors.As(err, &cerr) || cerr.Field != "SentinelStickiness" {
+		t.Errorf("validateConfig() error = %v, want invalid SentinelStickiness", err)
+	}
+}
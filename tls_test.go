@@ -0,0 +1,134 @@
+package sentinel
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// selfSigned returns self-signed certificate valid for 127.0.0.1 and
+// sentinel.test, and the pool of roots trusting it.
+func selfSigned(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "sentinel"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:              []string{"sentinel.test"},
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, roots
+}
+
+// newFakeTLS starts a fake server accepting TLS connections only.
+func newFakeTLS(t *testing.T, h func(args []string) string, cert tls.Certificate) *fakeSentinel {
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return serveFake(t, ln, h, 0)
+}
+
+func TestSentinelTLS(t *testing.T) {
+	cert, roots := selfSigned(t)
+	s := newFakeTLS(t, basicHandler, cert)
+
+	tests := []struct {
+		name string
+		opts []ClientOption
+	}{
+		{"RootCAs", []ClientOption{WithTLS(&tls.Config{RootCAs: roots})}},
+		{"InsecureSkipVerify", []ClientOption{WithTLS(&tls.Config{InsecureSkipVerify: true})}},
+		{"DialTLSSkipVerify", []ClientOption{WithTLS(nil), WithDialOptions(redis.DialTLSSkipVerify(true))}},
+	}
+	for _, tt := range tests {
+		c := NewClientWithOptions([]string{s.addr()}, tt.opts...)
+		_, err := c.MasterAddress("mymaster")
+		c.Close()
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+	}
+}
+
+func TestSentinelTLSVerifyErrors(t *testing.T) {
+	cert, _ := selfSigned(t)
+	a, b := newFakeTLS(t, basicHandler, cert), newFakeTLS(t, basicHandler, cert)
+	c := NewClientWithOptions([]string{a.addr(), b.addr()}, WithTLS(nil))
+	defer c.Close()
+
+	_, err := c.MasterAddress("mymaster")
+	var nerr *NoSentinelReachableError
+	if !errors.As(err, &nerr) {
+		t.Fatalf("MasterAddress() error = %v, want *NoSentinelReachableError", err)
+	}
+	attempts := nerr.Attempts()
+	if len(attempts) != 2 {
+		t.Fatalf("Attempts() = %v, want 2", attempts)
+	}
+	for i, addr := range []string{a.addr(), b.addr()} {
+		var uerr x509.UnknownAuthorityError
+		if attempts[i].Sentinel != addr || !errors.As(attempts[i].Err, &uerr) {
+			t.Errorf("attempt %d = %s: %v, want unknown authority error of %s", i, attempts[i].Sentinel, attempts[i].Err, addr)
+		}
+	}
+}
+
+func TestPoolSentinelTLS(t *testing.T) {
+	cert, roots := selfSigned(t)
+	master := newFake(t, roleMaster)
+	s := newFakeTLS(t, pointTo(master.addr()), cert)
+
+	conf := testConfig(s.addr())
+	conf.SentinelTLS = &tls.Config{RootCAs: roots}
+	p, err := NewPool(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	c, err := p.DialContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	conf.SentinelTLS = nil
+	plain, err := NewPool(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.Close()
+	if c, err := plain.DialContext(context.Background()); err == nil {
+		c.Close()
+		t.Fatal("TLS sentinel queried over plain TCP")
+	}
+}
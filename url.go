@@ -0,0 +1,229 @@
+package sentinel
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultSentinelPort is the port assumed for sentinel addresses without one.
+const defaultSentinelPort = "26379"
+
+// ParseURL parses sentinel connection string into Config based on
+// DefaultConfig. The format is
+//
+//	redis+sentinel://[user[:password]@]host[:port][,host[:port]...]/master[?options]
+//
+// Credentials are used to authenticate redis connections. Sentinel port
+// defaults to 26379, IPv6 hosts must be enclosed in brackets. Scheme
+// rediss+sentinel enables TLS for redis connections. Supported options are:
+//
+//	db                      redis database, see Config.RedisDB
+//	sentinel-username       sentinel credentials
+//	sentinel-password
+//	dial-timeout            redis connect, read and write timeouts
+//	read-timeout
+//	write-timeout
+//	sentinel-dial-timeout   sentinel connect, read, write and total timeouts
+//	sentinel-read-timeout
+//	sentinel-write-timeout
+//	sentinel-total-timeout
+//	master-cache-ttl        see Config.MasterCacheTTL
+//
+// Timeouts are in time.ParseDuration format. Returned errors never include the
+// passwords. Config.URL formats Config back into this format.
+func ParseURL(raw string) (Config, error) {
+	scheme, rest, ok := strings.Cut(raw, "://")
+	if !ok {
+		return Config{}, errors.New("sentinel: invalid url: missing scheme")
+	}
+	var useTLS bool
+	switch strings.ToLower(scheme) {
+	case "redis+sentinel":
+	case "rediss+sentinel":
+		useTLS = true
+	default:
+		return Config{}, fmt.Errorf("sentinel: invalid url scheme %q, expected redis+sentinel or rediss+sentinel", scheme)
+	}
+
+	rest, query, _ := strings.Cut(rest, "?")
+	authority, path, _ := strings.Cut(rest, "/")
+
+	var username, password string
+	if i := strings.LastIndex(authority, "@"); i >= 0 {
+		var err error
+		if username, password, err = parseUserinfo(authority[:i]); err != nil {
+			return Config{}, err
+		}
+		authority = authority[i+1:]
+	}
+
+	sentinels, err := parseHosts(authority)
+	if err != nil {
+		return Config{}, err
+	}
+
+	master, err := url.PathUnescape(strings.TrimSuffix(path, "/"))
+	if err != nil {
+		return Config{}, fmt.Errorf("sentinel: invalid master name %q: %w", path, err)
+	}
+	if master == "" || strings.Contains(master, "/") {
+		return Config{}, fmt.Errorf("sentinel: invalid url: master name %q expected as the only path element", master)
+	}
+
+	conf := DefaultConfig(master, sentinels...)
+	conf.RedisUsername = username
+	conf.RedisPassword = password
+	conf.RedisUseTLS = useTLS
+
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return Config{}, fmt.Errorf("sentinel: invalid url options: %w", err)
+	}
+	for key, vals := range values {
+		if err := conf.setURLOption(key, vals[len(vals)-1]); err != nil {
+			return Config{}, err
+		}
+	}
+	return conf, nil
+}
+
+// URL formats Config as connection string accepted by ParseURL. Passwords
+// are redacted, so it is safe to log. Options are included only if they
+// differ from DefaultConfig, fields not supported by ParseURL are left out.
+func (conf Config) URL() string {
+	scheme := "redis+sentinel"
+	if conf.RedisUseTLS {
+		scheme = "rediss+sentinel"
+	}
+	var b strings.Builder
+	b.WriteString(scheme + "://")
+	if conf.RedisUsername != "" || conf.RedisPassword != "" {
+		b.WriteString(escapeUserinfo(conf.RedisUsername))
+		if conf.RedisPassword != "" {
+			b.WriteString(":" + redacted)
+		}
+		b.WriteString("@")
+	}
+	b.WriteString(strings.Join(conf.Sentinels, ","))
+	b.WriteString("/" + url.PathEscape(conf.Master))
+
+	def := DefaultConfig(conf.Master)
+	values := url.Values{}
+	if conf.RedisDB != 0 {
+		values.Set("db", strconv.Itoa(conf.RedisDB))
+	}
+	if conf.SentinelUsername != "" {
+		values.Set("sentinel-username", conf.SentinelUsername)
+	}
+	if conf.SentinelPassword != "" {
+		values.Set("sentinel-password", redacted)
+	}
+	for _, opt := range []struct {
+		key      string
+		v, deflt time.Duration
+	}{
+		{"dial-timeout", conf.RedisTimeouts.Connect, def.RedisTimeouts.Connect},
+		{"read-timeout", conf.RedisTimeouts.Read, def.RedisTimeouts.Read},
+		{"write-timeout", conf.RedisTimeouts.Write, def.RedisTimeouts.Write},
+		{"sentinel-dial-timeout", conf.SentinelTimeouts.Connect, def.SentinelTimeouts.Connect},
+		{"sentinel-read-timeout", conf.SentinelTimeouts.Read, def.SentinelTimeouts.Read},
+		{"sentinel-write-timeout", conf.SentinelTimeouts.Write, def.SentinelTimeouts.Write},
+		{"sentinel-total-timeout", conf.SentinelTimeouts.Total, def.SentinelTimeouts.Total},
+		{"master-cache-ttl", conf.MasterCacheTTL, def.MasterCacheTTL},
+	} {
+		if opt.v != opt.deflt {
+			values.Set(opt.key, opt.v.String())
+		}
+	}
+	if len(values) > 0 {
+		b.WriteString("?" + values.Encode())
+	}
+	return b.String()
+}
+
+// escapeUserinfo percent encodes user name, so it can be parsed back by
+// parseUserinfo.
+func escapeUserinfo(s string) string {
+	return strings.NewReplacer(":", "%3A", "@", "%40").Replace(url.PathEscape(s))
+}
+
+// parseUserinfo parses percent encoded "user[:password]".
+func parseUserinfo(s string) (username, password string, err error) {
+	user, pass, _ := strings.Cut(s, ":")
+	if username, err = url.PathUnescape(user); err != nil {
+		return "", "", fmt.Errorf("sentinel: invalid url username: %w", err)
+	}
+	if password, err = url.PathUnescape(pass); err != nil {
+		// Error is not wrapped to avoid leaking the password.
+		return "", "", errors.New("sentinel: invalid url password encoding")
+	}
+	return username, password, nil
+}
+
+// parseHosts parses comma separated list of sentinel addresses.
+func parseHosts(s string) ([]string, error) {
+	if s == "" {
+		return nil, errors.New("sentinel: invalid url: no sentinel hosts")
+	}
+	var addrs []string
+	for _, host := range strings.Split(s, ",") {
+		if host == "" {
+			return nil, fmt.Errorf("sentinel: invalid url host list %q: empty host", s)
+		}
+		addr, err := normalizeAddr(host)
+		if err != nil {
+			return nil, fmt.Errorf("sentinel: invalid url host: %w", err)
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+// setURLOption sets Config field from the ParseURL option.
+func (conf *Config) setURLOption(key, value string) error {
+	var d *time.Duration
+	switch key {
+	case "db":
+		db, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("sentinel: invalid url option db=%q: %w", value, err)
+		}
+		conf.RedisDB = db
+		return nil
+	case "sentinel-username":
+		conf.SentinelUsername = value
+		return nil
+	case "sentinel-password":
+		conf.SentinelPassword = value
+		return nil
+	case "dial-timeout":
+		d = &conf.RedisTimeouts.Connect
+	case "read-timeout":
+		d = &conf.RedisTimeouts.Read
+	case "write-timeout":
+		d = &conf.RedisTimeouts.Write
+	case "sentinel-dial-timeout":
+		d = &conf.SentinelTimeouts.Connect
+	case "sentinel-read-timeout":
+		d = &conf.SentinelTimeouts.Read
+	case "sentinel-write-timeout":
+		d = &conf.SentinelTimeouts.Write
+	case "sentinel-total-timeout":
+		d = &conf.SentinelTimeouts.Total
+	case "master-cache-ttl":
+		d = &conf.MasterCacheTTL
+	default:
+		return fmt.Errorf("sentinel: unknown url option %q", key)
+	}
+
+	v, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("sentinel: invalid url option %s=%q: %w", key, value, err)
+	}
+	*d = v
+	return nil
+}
@@ -0,0 +1,296 @@
+package sentinel
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// defaultWaitBackoff is the delay between the attempts of WaitForMaster if
+// not set by WithWaitBackoff.
+var defaultWaitBackoff = RetryBackoff{Base: 100 * time.Millisecond, Multiplier: 2, Jitter: 0.2, Max: 5 * time.Second}
+
+// WaitOption is an optional configuration of WaitForMaster, FailoverAndWait
+// and WaitForReplicaSync.
+type WaitOption func(*waitOptions)
+
+type waitOptions struct {
+	backoff     RetryBackoff
+	roleCheck   bool
+	conf        *Config
+	dialOptions []redis.DialOption
+}
+
+// WithWaitBackoff sets the delays between the attempts of WaitForMaster. By
+// default the delay starts at 100ms and doubles up to 5s. Zero Base retries
+// without delay.
+func WithWaitBackoff(b RetryBackoff) WaitOption {
+	return func(o *waitOptions) {
+		o.backoff = b
+	}
+}
+
+// WithWaitRoleCheck makes WaitForMaster dial the master address and check its
+// role with TestRole, so an address of a server not yet promoted or
+// unreachable is not accepted. FailoverAndWait always checks the role. Master
+// is dialed as configured by WithWaitConfig and WithWaitDialOptions.
+func WithWaitRoleCheck() WaitOption {
+	return func(o *waitOptions) {
+		o.roleCheck = true
+	}
+}
+
+// WithWaitConfig makes FailoverAndWait, WaitForReplicaSync and the role check
+// of WaitForMaster dial the master the way pools created from conf do, with
+// its credentials, TLS settings, dialer and AddressTranslator. Sentinel and
+// pool settings of conf are ignored. Master is dialed with no options by
+// default.
+func WithWaitConfig(conf Config) WaitOption {
+	return func(o *waitOptions) {
+		o.conf = &conf
+	}
+}
+
+// WithWaitDialOptions adds options for dialing the master by FailoverAndWait,
+// WaitForReplicaSync and the role check of WaitForMaster. They are applied
+// after the ones of WithWaitConfig.
+func WithWaitDialOptions(options ...redis.DialOption) WaitOption {
+	return func(o *waitOptions) {
+		o.dialOptions = append(o.dialOptions[:len(o.dialOptions):len(o.dialOptions)], options...)
+	}
+}
+
+// dial connects to the redis server at addr announced by sentinel. The
+// address dialed is returned, translated if configured by WithWaitConfig.
+func (o waitOptions) dial(ctx context.Context, addr string) (redis.Conn, string, error) {
+	options := o.dialOptions
+	if o.conf != nil {
+		addr = o.conf.translateAddr(addr)
+		options = append(o.conf.redisDialOptions(), options...)
+	}
+	c, err := redis.DialContext(ctx, "tcp", addr, options...)
+	if isAuthError(err) {
+		return nil, addr, &AuthError{Addr: addr, Err: err}
+	}
+	if err != nil {
+		return nil, addr, wrapError("dial error", err)
+	}
+	if o.conf != nil {
+		if err := setClientName(c, o.conf.ClientName); err != nil {
+			c.Close()
+			return nil, addr, wrapError("dial: set client name", err)
+		}
+	}
+	return c, addr, nil
+}
+
+// WaitForMaster polls the client for the address of the named master until it
+// is resolved or ctx is done, e.g. to wait out a failover in progress during
+// service startup. The address settled on is returned. Error returned once ctx
+// is done wraps both the context error and the error of the last attempt.
+func WaitForMaster(ctx context.Context, client *Client, name string, opts ...WaitOption) (string, error) {
+	o := waitOptions{backoff: defaultWaitBackoff}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	for failed := 0; ; failed++ {
+		addr, err := waitAttempt(ctx, client, name, o)
+		if err == nil {
+			return addr, nil
+		}
+		// Address might be cached or stale, look it up again.
+		client.Invalidate(name)
+		t := time.NewTimer(o.backoff.delay(failed + 1))
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return "", fmt.Errorf("sentinel: wait for master %q: %w: %w", name, ctx.Err(), err)
+		}
+	}
+}
+
+// waitAttempt looks up the master address once and checks its role if
+// enabled.
+func waitAttempt(ctx context.Context, client *Client, name string, o waitOptions) (string, error) {
+	addr, err := client.MasterAddressContext(ctx, name)
+	if err != nil || !o.roleCheck {
+		return addr, err
+	}
+	if err := checkMasterRole(ctx, addr, o); err != nil {
+		return "", err
+	}
+	return addr, nil
+}
+
+// checkMasterRole dials the server at addr to check it is master.
+func checkMasterRole(ctx context.Context, addr string, o waitOptions) error {
+	c, addr, err := o.dial(ctx, addr)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return testRoleAt(c, addr, "master")
+}
+
+// FailoverAndWait forces a failover of the named master, see Failover, and
+// waits until the master address changes and the new master passes the role
+// check, or ctx is done. The master address before and after the failover is
+// returned. Address changes are learned from +switch-master events, sentinel
+// is polled as well in case the subscription fails or misses the event.
+//
+// Options configure the polling delays and how the master is dialed for the
+// role check, which is always done, see WithWaitConfig. ErrNoGoodSlave or
+// ErrFailoverInProgress is returned if sentinel refuses to start the
+// failover.
+func (sc *Client) FailoverAndWait(ctx context.Context, name string, opts ...WaitOption) (old, new string, err error) {
+	o := waitOptions{backoff: defaultWaitBackoff}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	sc.Invalidate(name)
+	if old, err = sc.MasterAddressContext(ctx, name); err != nil {
+		return "", "", err
+	}
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// Subscribe before the failover is started not to miss the event.
+	// Sentinel is still polled if the subscription fails.
+	switches, _ := sc.WatchMaster(wctx, name)
+	if err = sc.Failover(name); err != nil {
+		return old, "", err
+	}
+
+	var lastErr error
+	for polls := 1; ; {
+		t := time.NewTimer(o.backoff.delay(polls))
+		var addr string
+		select {
+		case ev, ok := <-switches:
+			if !ok {
+				switches = nil
+				break
+			}
+			addr = ev.New.String()
+		case <-t.C:
+			polls++
+			sc.Invalidate(name)
+			addr, lastErr = sc.MasterAddressContext(ctx, name)
+		case <-ctx.Done():
+			t.Stop()
+			if lastErr != nil {
+				return old, "", fmt.Errorf("sentinel: wait for failover of %q: %w: %w", name, ctx.Err(), lastErr)
+			}
+			return old, "", fmt.Errorf("sentinel: wait for failover of %q: %w", name, ctx.Err())
+		}
+		t.Stop()
+		if addr == "" || addr == old {
+			continue
+		}
+		if lastErr = checkMasterRole(ctx, addr, o); lastErr == nil {
+			return old, addr, nil
+		}
+	}
+}
+
+// ReplicaSync is the replication state of a single replica reported by
+// WaitForReplicaSync.
+type ReplicaSync struct {
+	Addr string
+	// Offset is the replication offset acknowledged by the replica, -1 if
+	// replica is not connected to the master.
+	Offset int64
+	// Lag is the number of bytes replica is behind the master offset.
+	Lag int64
+	// Synced reports if Lag is within the limit.
+	Synced bool
+}
+
+// WaitForReplicaSync waits until every replica of the named master known to
+// sentinel and not flagged down is connected to the master and its
+// replication offset is at most maxLag bytes behind the master offset, or ctx
+// is done. Offsets are read from the ROLE reply of the master, which is
+// dialed as configured by WithWaitConfig and WithWaitDialOptions. The state
+// of the replicas seen last is returned, also together with the error once
+// ctx is done, to tell which replicas did not catch up.
+func WaitForReplicaSync(ctx context.Context, client *Client, name string, maxLag int64, opts ...WaitOption) ([]ReplicaSync, error) {
+	o := waitOptions{backoff: defaultWaitBackoff}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var report []ReplicaSync
+	for failed := 0; ; failed++ {
+		seen, synced, err := replicaSync(ctx, client, name, maxLag, o)
+		if err == nil {
+			report = seen
+			if synced {
+				return report, nil
+			}
+		}
+		t := time.NewTimer(o.backoff.delay(failed + 1))
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			if err != nil {
+				return report, fmt.Errorf("sentinel: wait for replicas of %q: %w: %w", name, ctx.Err(), err)
+			}
+			return report, fmt.Errorf("sentinel: wait for replicas of %q: %w", name, ctx.Err())
+		}
+	}
+}
+
+// replicaSync compares the offsets of the master replicas once.
+func replicaSync(ctx context.Context, client *Client, name string, maxLag int64, o waitOptions) ([]ReplicaSync, bool, error) {
+	replicas, err := client.Replicas(name)
+	if err != nil {
+		return nil, false, err
+	}
+	addr, err := client.MasterAddressContext(ctx, name)
+	if err != nil {
+		return nil, false, err
+	}
+	c, addr, err := o.dial(ctx, addr)
+	if err != nil {
+		return nil, false, err
+	}
+	defer c.Close()
+	role, err := RoleInfo(c)
+	if err != nil {
+		return nil, false, err
+	}
+	if role.Role != "master" {
+		return nil, false, &RoleError{Expected: "master", Actual: role.Role, Addr: addr}
+	}
+
+	offsets := make(map[string]int64, len(role.Replicas))
+	for _, r := range role.Replicas {
+		offsets[r.Addr.String()] = r.Offset
+	}
+	report := make([]ReplicaSync, 0, len(replicas))
+	synced := true
+	for _, r := range replicas {
+		if r.down() {
+			continue
+		}
+		rs := ReplicaSync{Addr: r.Addr().String(), Offset: -1, Lag: role.Offset}
+		if offset, ok := offsets[rs.Addr]; ok {
+			rs.Offset = offset
+			rs.Lag = role.Offset - offset
+			if rs.Lag < 0 {
+				rs.Lag = 0
+			}
+			rs.Synced = rs.Lag <= maxLag
+		}
+		if !rs.Synced {
+			synced = false
+		}
+		report = append(report, rs)
+	}
+	return report, synced, nil
+}
@@ -0,0 +1,277 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// replicaRole is the ROLE reply of replica connected to master.
+const replicaRole = "*5\r\n$5\r\nslave\r\n$9\r\n127.0.0.1\r\n:1\r\n$9\r\nconnected\r\n:0\r\n"
+
+var fastWait = WithWaitBackoff(RetryBackoff{Base: 10 * time.Millisecond})
+
+func TestWaitForMaster(t *testing.T) {
+	var known, promoted int32
+	master := newFake(t, func(args []string) string {
+		if strings.EqualFold(args[0], "ROLE") {
+			if atomic.LoadInt32(&promoted) == 0 {
+				return replicaRole
+			}
+			return masterRole
+		}
+		return "+OK\r\n"
+	})
+	host, port, _ := net.SplitHostPort(master.addr())
+	s := newFake(t, func(args []string) string {
+		if strings.EqualFold(args[0], "SENTINEL") {
+			if atomic.LoadInt32(&known) == 0 {
+				return "*-1\r\n"
+			}
+			return bulkArr(host, port)
+		}
+		return basicHandler(args)
+	})
+	c := NewClientWithOptions([]string{s.addr()}, WithMasterCache(time.Minute))
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	_, err := WaitForMaster(ctx, c, "mymaster", fastWait)
+	cancel()
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, ErrMasterUnknown) {
+		t.Fatalf("WaitForMaster() error = %v, want deadline exceeded and ErrMasterUnknown", err)
+	}
+
+	atomic.StoreInt32(&known, 1)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		atomic.StoreInt32(&promoted, 1)
+	}()
+	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	addr, err := WaitForMaster(ctx, c, "mymaster", fastWait, WithWaitRoleCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr != master.addr() || atomic.LoadInt32(&promoted) != 1 {
+		t.Fatalf("WaitForMaster() = %s before promotion", addr)
+	}
+}
+
+func TestFailoverAndWait(t *testing.T) {
+	var promoted int32
+	roleNode := func(isMaster func() bool) *fakeSentinel {
+		return newFake(t, func(args []string) string {
+			if strings.EqualFold(args[0], "ROLE") {
+				if isMaster() {
+					return masterRole
+				}
+				return replicaRole
+			}
+			return "+OK\r\n"
+		})
+	}
+	a := roleNode(func() bool { return atomic.LoadInt32(&promoted) == 0 })
+	b := roleNode(func() bool { return atomic.LoadInt32(&promoted) == 2 })
+	var refuse int32 = 1
+	s := newFake(t, func(args []string) string {
+		if !strings.EqualFold(args[0], "SENTINEL") {
+			return basicHandler(args)
+		}
+		switch strings.ToUpper(args[1]) {
+		case "FAILOVER":
+			if atomic.LoadInt32(&refuse) == 1 {
+				return "-NOGOODSLAVE No suitable replica to promote\r\n"
+			}
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				atomic.StoreInt32(&promoted, 1)
+				// Sentinel switches before the replica reports master.
+				time.Sleep(100 * time.Millisecond)
+				atomic.StoreInt32(&promoted, 2)
+			}()
+			return "+OK\r\n"
+		case "GET-MASTER-ADDR-BY-NAME":
+			addr := a.addr()
+			if atomic.LoadInt32(&promoted) > 0 {
+				addr = b.addr()
+			}
+			host, port, _ := net.SplitHostPort(addr)
+			return bulkArr(host, port)
+		}
+		return basicHandler(args)
+	})
+	c := NewClient([]string{s.addr()})
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if _, _, err := c.FailoverAndWait(ctx, "mymaster", fastWait); !errors.Is(err, ErrNoGoodSlave) {
+		t.Fatalf("FailoverAndWait() error = %v, want ErrNoGoodSlave", err)
+	}
+	atomic.StoreInt32(&refuse, 0)
+	old, new, err := c.FailoverAndWait(ctx, "mymaster", fastWait)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old != a.addr() || new != b.addr() || atomic.LoadInt32(&promoted) != 2 {
+		t.Fatalf("FailoverAndWait() = %s, %s before promotion", old, new)
+	}
+}
+
+func TestFailoverAndWaitTimeout(t *testing.T) {
+	s := newFake(t, func(args []string) string {
+		if strings.EqualFold(args[0], "SENTINEL") && strings.EqualFold(args[1], "FAILOVER") {
+			return "+OK\r\n"
+		}
+		return basicHandler(args)
+	})
+	c := NewClient([]string{s.addr()})
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if _, _, err := c.FailoverAndWait(ctx, "mymaster", fastWait); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("FailoverAndWait() error = %v, want deadline exceeded", err)
+	}
+}
+
+// replicaEntry formats replica of SENTINEL replicas reply.
+func replicaEntry(ip, port, flags string) string {
+	return bulkArr("name", ip+":"+port, "ip", ip, "port", port, "flags", flags, "master-link-status", "ok")
+}
+
+// masterRoleWith formats ROLE reply of master at offset with replicas
+// 10.0.0.2 at the given ports and offsets.
+func masterRoleWith(offset int64, replicas ...[2]int64) string {
+	s := "*3\r\n" + bulk("master") + ":" + strconv.FormatInt(offset, 10) + "\r\n*" + strconv.Itoa(len(replicas)) + "\r\n"
+	for _, r := range replicas {
+		s += bulkArr("10.0.0.2", strconv.FormatInt(r[0], 10), strconv.FormatInt(r[1], 10))
+	}
+	return s
+}
+
+func TestWaitForReplicaSync(t *testing.T) {
+	var offset int64 = 100
+	master := newFake(t, func(args []string) string {
+		if strings.EqualFold(args[0], "ROLE") {
+			return masterRoleWith(1000, [2]int64{6380, 995}, [2]int64{6381, atomic.LoadInt64(&offset)})
+		}
+		return "+OK\r\n"
+	})
+	host, port, _ := net.SplitHostPort(master.addr())
+	s := newFake(t, func(args []string) string {
+		if !strings.EqualFold(args[0], "SENTINEL") {
+			return basicHandler(args)
+		}
+		switch strings.ToUpper(args[1]) {
+		case "REPLICAS":
+			return "*3\r\n" + replicaEntry("10.0.0.2", "6380", "slave") + replicaEntry("10.0.0.2", "6381", "slave") +
+				replicaEntry("10.0.0.2", "6382", "slave,s_down")
+		case "GET-MASTER-ADDR-BY-NAME":
+			return bulkArr(host, port)
+		}
+		return basicHandler(args)
+	})
+	c := NewClient([]string{s.addr()})
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Millisecond)
+	report, err := WaitForReplicaSync(ctx, c, "mymaster", 10, fastWait)
+	cancel()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("WaitForReplicaSync() error = %v, want deadline exceeded", err)
+	}
+	want := []ReplicaSync{
+		{Addr: "10.0.0.2:6380", Offset: 995, Lag: 5, Synced: true},
+		{Addr: "10.0.0.2:6381", Offset: 100, Lag: 900},
+	}
+	if len(report) != len(want) || report[0] != want[0] || report[1] != want[1] {
+		t.Fatalf("WaitForReplicaSync() = %+v, want %+v", report, want)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		atomic.StoreInt64(&offset, 998)
+	}()
+	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	report, err = WaitForReplicaSync(ctx, c, "mymaster", 10, fastWait)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report) != 2 || !report[1].Synced || report[1].Lag != 2 {
+		t.Fatalf("WaitForReplicaSync() = %+v", report)
+	}
+}
+
+func TestWaitDialSettings(t *testing.T) {
+	var authed int32
+	master := newFake(t, func(args []string) string {
+		switch strings.ToUpper(args[0]) {
+		case "AUTH":
+			if len(args) == 3 && args[1] == "app" && args[2] == "secret" {
+				atomic.StoreInt32(&authed, 1)
+				return "+OK\r\n"
+			}
+			return "-WRONGPASS invalid username-password pair\r\n"
+		case "ROLE":
+			if atomic.LoadInt32(&authed) == 0 {
+				return "-NOAUTH Authentication required.\r\n"
+			}
+			return masterRoleWith(10)
+		}
+		return "+OK\r\n"
+	})
+	_, port, _ := net.SplitHostPort(master.addr())
+	// Sentinel announces the master at an address reachable only through
+	// the translator.
+	s := newFake(t, func(args []string) string {
+		if strings.EqualFold(args[0], "SENTINEL") && strings.EqualFold(args[1], "replicas") {
+			return "*0\r\n"
+		}
+		return pointTo(net.JoinHostPort("192.0.2.1", port))(args)
+	})
+	c := NewClient([]string{s.addr()})
+	defer c.Close()
+
+	conf := testConfig(s.addr())
+	conf.RedisUsername, conf.RedisPassword = "app", "secret"
+	conf.AddressTranslator = func(a Addr) Addr {
+		if a.Host == "192.0.2.1" {
+			a.Host = "127.0.0.1"
+		}
+		return a
+	}
+	tests := []struct {
+		name string
+		opts []WaitOption
+	}{
+		{"config", []WaitOption{WithWaitConfig(conf)}},
+		{"dial options", []WaitOption{
+			WithWaitConfig(Config{AddressTranslator: conf.AddressTranslator}),
+			WithWaitDialOptions(redis.DialConnectTimeout(time.Second), redis.DialUsername("app"), redis.DialPassword("secret")),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&authed, 0)
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			opts := append([]WaitOption{fastWait, WithWaitRoleCheck()}, tt.opts...)
+			if _, err := WaitForMaster(ctx, c, "mymaster", opts...); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := WaitForReplicaSync(ctx, c, "mymaster", 0, opts...); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,418 @@
+package sentinel
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	// watchPingInterval is the interval of health check pings sent over the
+	// pub/sub connection to detect broken sentinel connections.
+	watchPingInterval = 10 * time.Second
+	// watchBackoffBase and watchBackoffMax bound the exponential backoff
+	// between attempts to resume a dropped subscription.
+	watchBackoffBase = 100 * time.Millisecond
+	watchBackoffMax  = 10 * time.Second
+	// watchBufferSize is the number of events buffered for every watched
+	// master before the oldest ones are dropped.
+	watchBufferSize = 16
+)
+
+// MasterSwitch is an event announced by sentinel when the master address
+// changes after a failover.
+type MasterSwitch struct {
+	Name string
+	Old  Addr
+	New  Addr
+}
+
+// WatchMaster subscribes to the +switch-master events of the named master.
+// Events are delivered over the returned channel, which is closed once ctx is
+// done. Error is returned if none of the sentinels can be subscribed to
+// initially. See WatchMasters for details on resuming dropped subscriptions
+// and buffering of events.
+func (sc *Client) WatchMaster(ctx context.Context, name string) (<-chan MasterSwitch, error) {
+	w, err := sc.WatchMasters(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return w.Channel(name), nil
+}
+
+// MasterWatcher delivers +switch-master events of multiple masters received
+// over a single sentinel subscription. Events of every watched master are
+// delivered over its own channel.
+type MasterWatcher struct {
+	sc  *Client
+	ctx context.Context
+
+	mu      sync.Mutex
+	masters map[string]*watchedMaster
+}
+
+// watchedMaster is a delivery channel of a single master watched by
+// MasterWatcher.
+type watchedMaster struct {
+	ch chan MasterSwitch
+	// last is the last known master address, guarded by MasterWatcher.mu.
+	last Addr
+
+	// mu is held while sending to ch, so it is never closed during a send.
+	mu     sync.Mutex
+	closed bool
+}
+
+// WatchMasters subscribes to the +switch-master events of all the named
+// masters using a single sentinel connection. Watched masters can be added and
+// removed without interrupting the subscription. All the delivery channels are
+// closed once ctx is done. Error is returned if none of the sentinels can be
+// subscribed to initially.
+//
+// Delivery channels are buffered, so a slow consumer of one master does not
+// hold up the others. Once the buffer of a master is full, its oldest event
+// is dropped in favour of the new one.
+//
+// If the subscribed sentinel connection drops the subscription is resumed on
+// the next sentinel of the current sentinel list, backing off between the
+// attempts. Once resumed, master addresses are looked up again bypassing the
+// master cache and a MasterSwitch is delivered for every master whose address
+// has changed in the meantime, so switches announced while disconnected are
+// not lost.
+func (sc *Client) WatchMasters(ctx context.Context, names ...string) (*MasterWatcher, error) {
+	w := &MasterWatcher{
+		sc:      sc,
+		ctx:     ctx,
+		masters: make(map[string]*watchedMaster),
+	}
+
+	s := sc.newSubscription([]string{ChannelSwitchMaster})
+	s.handle = func(channel, payload string) {
+		if ev, err := parseMasterSwitch(payload); err == nil {
+			w.deliver(ev)
+		}
+	}
+	s.reconnected = func(string) {
+		w.resync()
+	}
+
+	psc, err := s.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		w.Add(name)
+	}
+	go func() {
+		defer w.stop()
+		s.run(ctx, psc)
+	}()
+	return w, nil
+}
+
+// Add starts watching the named master and returns its delivery channel. If
+// the master is already watched its existing channel is returned.
+func (w *MasterWatcher) Add(name string) <-chan MasterSwitch {
+	w.mu.Lock()
+	if m, ok := w.masters[name]; ok {
+		w.mu.Unlock()
+		return m.ch
+	}
+	m := &watchedMaster{ch: make(chan MasterSwitch, watchBufferSize)}
+	if w.masters == nil {
+		// Watcher is already stopped.
+		w.mu.Unlock()
+		m.close()
+		return m.ch
+	}
+	w.masters[name] = m
+	w.mu.Unlock()
+
+	if addr, err := w.sc.MasterAddrContext(w.ctx, name); err == nil {
+		w.mu.Lock()
+		if m.last == (Addr{}) {
+			m.last = addr
+		}
+		w.mu.Unlock()
+	}
+	return m.ch
+}
+
+// Remove stops watching the named master and closes its delivery channel.
+func (w *MasterWatcher) Remove(name string) {
+	w.mu.Lock()
+	m, ok := w.masters[name]
+	delete(w.masters, name)
+	w.mu.Unlock()
+
+	if ok {
+		m.close()
+	}
+}
+
+// Channel returns delivery channel of the named master, nil if the master is
+// not watched.
+func (w *MasterWatcher) Channel(name string) <-chan MasterSwitch {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if m, ok := w.masters[name]; ok {
+		return m.ch
+	}
+	return nil
+}
+
+// deliver sends the event to the watched master channel. Events of masters
+// not watched are ignored.
+func (w *MasterWatcher) deliver(ev MasterSwitch) {
+	w.mu.Lock()
+	m, ok := w.masters[ev.Name]
+	if ok {
+		m.last = ev.New
+	}
+	w.mu.Unlock()
+
+	if ok {
+		m.send(ev)
+	}
+}
+
+// resync looks up addresses of all the watched masters and delivers events
+// for those that have changed since last known.
+func (w *MasterWatcher) resync() {
+	w.mu.Lock()
+	names := make([]string, 0, len(w.masters))
+	for name := range w.masters {
+		names = append(names, name)
+	}
+	w.mu.Unlock()
+
+	for _, name := range names {
+		w.sc.Invalidate(name)
+		addr, err := w.sc.MasterAddrContext(w.ctx, name)
+		if err != nil {
+			continue
+		}
+		w.mu.Lock()
+		m, ok := w.masters[name]
+		var last Addr
+		if ok {
+			last = m.last
+		}
+		w.mu.Unlock()
+		if ok && last != (Addr{}) && last != addr {
+			w.deliver(MasterSwitch{Name: name, Old: last, New: addr})
+		}
+	}
+}
+
+// stop closes all the delivery channels.
+func (w *MasterWatcher) stop() {
+	w.mu.Lock()
+	masters := w.masters
+	w.masters = nil
+	w.mu.Unlock()
+
+	for _, m := range masters {
+		m.close()
+	}
+}
+
+// send delivers the event without blocking. If the channel buffer is full
+// the oldest event is dropped to make room for it.
+func (m *watchedMaster) send(ev MasterSwitch) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return
+	}
+	for {
+		select {
+		case m.ch <- ev:
+			return
+		default:
+		}
+		select {
+		case <-m.ch:
+		default:
+		}
+	}
+}
+
+func (m *watchedMaster) close() {
+	m.mu.Lock()
+	m.closed = true
+	close(m.ch)
+	m.mu.Unlock()
+}
+
+// parseMasterSwitch parses +switch-master payload in
+// "<name> <old ip> <old port> <new ip> <new port>" format.
+func parseMasterSwitch(payload string) (MasterSwitch, error) {
+	parts := strings.Fields(payload)
+	if len(parts) != 5 {
+		return MasterSwitch{}, fmt.Errorf("sentinel: invalid +switch-master payload %q", payload)
+	}
+	oldAddr, err := parseAddr(parts[1:3])
+	if err != nil {
+		return MasterSwitch{}, err
+	}
+	newAddr, err := parseAddr(parts[3:5])
+	if err != nil {
+		return MasterSwitch{}, err
+	}
+	return MasterSwitch{Name: parts[0], Old: oldAddr, New: newAddr}, nil
+}
+
+// subscription is a pub/sub subscription to the sentinel channels that moves
+// over to the next sentinel when the subscribed connection drops. Sentinels
+// are taken from the current sentinel list of Client on every dial.
+type subscription struct {
+	sc       *Client
+	patterns []string
+	// last is the address of the sentinel dialed last.
+	last string
+
+	// handle is called for every received message.
+	handle func(channel, payload string)
+	// reconnected is called, if set, after the dropped subscription was
+	// resumed on the sentinel at addr.
+	reconnected func(addr string)
+}
+
+func (sc *Client) newSubscription(patterns []string) *subscription {
+	return &subscription{sc: sc, patterns: patterns}
+}
+
+// dial tries all sentinels once starting with the next one and returns
+// connection subscribed to the patterns.
+func (s *subscription) dial(ctx context.Context) (redis.PubSubConn, error) {
+	err := ErrNoSentinelsConfigured
+
+	for i, n := 0, s.sc.sentinelCount(); i < n; i++ {
+		if ctx.Err() != nil {
+			return redis.PubSubConn{}, wrapError("sentinel", ctx.Err())
+		}
+		var psc redis.PubSubConn
+		if psc, _, err = s.dialNext(ctx); err == nil {
+			return psc, nil
+		}
+	}
+
+	return redis.PubSubConn{}, err
+}
+
+// dialNext subscribes to the patterns on the sentinel following the one
+// dialed last, starting with the active sentinel.
+func (s *subscription) dialNext(ctx context.Context) (redis.PubSubConn, string, error) {
+	sc := s.sc
+	sc.Lock()
+	order := sc.order()
+	next := sc.activeAddr
+	for i, addr := range order {
+		if addr == s.last {
+			next = i + 1
+		}
+	}
+	var addr string
+	if len(order) > 0 {
+		addr = order[next%len(order)]
+	}
+	options, name := sc.options, sc.clientName
+	sc.Unlock()
+
+	if addr == "" {
+		return redis.PubSubConn{}, "", ErrNoSentinelsConfigured
+	}
+	s.last = addr
+	c, err := dialSentinel(ctx, addr, options, name)
+	if err != nil {
+		return redis.PubSubConn{}, addr, err
+	}
+	psc := redis.PubSubConn{Conn: c}
+	if err := psc.PSubscribe(redis.Args{}.AddFlat(s.patterns)...); err != nil {
+		psc.Close()
+		return redis.PubSubConn{}, addr, err
+	}
+	return psc, addr, nil
+}
+
+// run delivers received messages to handle until ctx is done. Broken
+// connections are replaced by dialing the next sentinel, with exponential
+// backoff between failed attempts.
+func (s *subscription) run(ctx context.Context, psc redis.PubSubConn) {
+	for {
+		s.serve(ctx, psc)
+		psc.Close()
+
+		var addr string
+		for attempt := 0; ; attempt++ {
+			if ctx.Err() != nil {
+				return
+			}
+			var err error
+			if psc, addr, err = s.dialNext(ctx); err == nil {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff(attempt, watchBackoffBase, watchBackoffMax)):
+			}
+		}
+		if s.reconnected != nil {
+			s.reconnected(addr)
+		}
+	}
+}
+
+// serve receives messages from the subscribed connection until it fails or
+// ctx is done.
+func (s *subscription) serve(ctx context.Context, psc redis.PubSubConn) {
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		t := time.NewTicker(watchPingInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				// Unblocks the pending receive.
+				psc.Close()
+				return
+			case <-done:
+				return
+			case <-t.C:
+				if err := psc.Ping(""); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		switch v := psc.ReceiveWithTimeout(2 * watchPingInterval).(type) {
+		case redis.Message:
+			s.handle(v.Channel, string(v.Data))
+		case error:
+			return
+		}
+	}
+}
+
+// backoff returns exponentially growing delay for the attempt, capped at max,
+// with a random jitter of up to a half of the delay.
+func backoff(attempt int, base, max time.Duration) time.Duration {
+	d := max
+	if attempt < 32 && base<<uint(attempt) < max {
+		d = base << uint(attempt)
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
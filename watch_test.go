@@ -0,0 +1,139 @@
+package sentinel
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// subscribed is the reply confirming subscription to +switch-master.
+var subscribed = "*3\r\n" + bulk("psubscribe") + bulk(ChannelSwitchMaster) + ":1\r\n"
+
+// switchMessage formats +switch-master message of the named master.
+func switchMessage(name string, oldPort, newPort int) string {
+	payload := fmt.Sprintf("%s 10.0.0.1 %d 10.0.0.1 %d", name, oldPort, newPort)
+	return bulkArr("pmessage", ChannelSwitchMaster, ChannelSwitchMaster, payload)
+}
+
+func receiveSwitch(t *testing.T, ch <-chan MasterSwitch) MasterSwitch {
+	t.Helper()
+	select {
+	case ev := <-ch:
+		return ev
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for master switch")
+	}
+	return MasterSwitch{}
+}
+
+func TestWatchMastersSlowConsumer(t *testing.T) {
+	const switches = 3 * watchBufferSize
+	s := newFake(t, func(args []string) string {
+		if strings.EqualFold(args[0], "PSUBSCRIBE") {
+			reply := subscribed
+			for i := 0; i < switches; i++ {
+				reply += switchMessage("slow", 7000+i, 7001+i)
+			}
+			return reply + switchMessage("fast", 6379, 6380)
+		}
+		return basicHandler(args)
+	})
+	c := NewClient([]string{s.addr()})
+	defer c.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w, err := c.WatchMasters(ctx, "slow", "fast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev := receiveSwitch(t, w.Channel("fast")); ev.New.Port != 6380 {
+		t.Fatalf("fast master switched to %v", ev.New)
+	}
+
+	slow := w.Channel("slow")
+	if n := len(slow); n != watchBufferSize {
+		t.Fatalf("%d events buffered, want %d", n, watchBufferSize)
+	}
+	var last MasterSwitch
+	for len(slow) > 0 {
+		last = <-slow
+	}
+	if last.New.Port != 7000+switches {
+		t.Errorf("last buffered switch to %v, want port %d", last.New, 7000+switches)
+	}
+}
+
+func TestWatchMastersResyncBypassesCache(t *testing.T) {
+	var mu sync.Mutex
+	subscriptions := 0
+	port := "6379"
+	s := newFake(t, func(args []string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		switch strings.ToUpper(args[0]) {
+		case "PSUBSCRIBE":
+			subscriptions++
+			if subscriptions == 1 {
+				// Master fails over while the subscription is down.
+				port = "6380"
+				return subscribed + closeAfter
+			}
+			return subscribed
+		case "SENTINEL":
+			return bulkArr("10.0.0.1", port)
+		}
+		return basicHandler(args)
+	})
+	c := NewClientWithOptions([]string{s.addr()}, WithMasterCache(time.Hour))
+	defer c.Close()
+	if _, err := c.MasterAddress("mymaster"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := c.WatchMaster(ctx, "mymaster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := receiveSwitch(t, ch)
+	if ev.Old.Port != 6379 || ev.New.Port != 6380 {
+		t.Fatalf("switch from %v to %v, want port 6379 to 6380", ev.Old, ev.New)
+	}
+}
+
+func TestWatchMastersFollowsSetAddrs(t *testing.T) {
+	release := make(chan struct{})
+	old := newFake(t, func(args []string) string {
+		if strings.EqualFold(args[0], "PSUBSCRIBE") {
+			<-release
+			return closeAfter
+		}
+		return basicHandler(args)
+	})
+	added := newFake(t, func(args []string) string {
+		if strings.EqualFold(args[0], "PSUBSCRIBE") {
+			return subscribed + switchMessage("mymaster", 6379, 6380)
+		}
+		return basicHandler(args)
+	})
+	c := NewClient([]string{old.addr()})
+	defer c.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := c.WatchMaster(ctx, "mymaster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetAddrs([]string{added.addr()})
+	close(release)
+
+	if ev := receiveSwitch(t, ch); ev.New.Port != 6380 {
+		t.Fatalf("switch to %v, want port 6380", ev.New)
+	}
+}